@@ -0,0 +1,109 @@
+package alerts
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// Monitor is an event sink watching the stock of the products. It raises an alert when the stock of a product
+// falls below its reorder threshold or runs out, and resolves it when the stock recovers.
+// An alert resolved and breached again within the cooldown of when it was raised is reopened without raising
+// it anew, so a stock hovering around the threshold does not raise an alert for every order.
+type Monitor struct {
+	store    *db.AlertDB
+	cooldown time.Duration
+	// raised holds when each alert was last raised
+	raised map[string]time.Time
+	// lastID is the id of the last event received, events are delivered at least once
+	lastID uint64
+	lock   sync.Mutex
+}
+
+// NewMonitor creates a monitor storing its alerts in the store
+func NewMonitor(store *db.AlertDB, cooldown time.Duration) *Monitor {
+	return &Monitor{
+		store:    store,
+		cooldown: cooldown,
+		raised:   make(map[string]time.Time),
+	}
+}
+
+func (m *Monitor) Name() string {
+	return "alerts"
+}
+
+// Publish checks the alerts of the product an event is about
+func (m *Monitor) Publish(e models.Event) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if e.ID <= m.lastID {
+		return nil
+	}
+	m.lastID = e.ID
+	if e.Product == nil {
+		return nil
+	}
+	switch e.Type {
+	case models.EventType_StockChanged, models.EventType_ProductUpdated:
+		m.check(*e.Product)
+	case models.EventType_ProductRemoved:
+		m.resolveAll(*e.Product)
+	}
+	return nil
+}
+
+// Alerts lists the alerts, only the open ones unless resolved ones are included, the latest raised first
+func (m *Monitor) Alerts(includeResolved bool) []models.Alert {
+	alerts := []models.Alert{}
+	for _, alert := range m.store.GetAll() {
+		if alert.Open || includeResolved {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// check raises, reopens and resolves the alerts of every level for the current state of the product
+func (m *Monitor) check(p models.Product) {
+	for _, level := range models.AlertLevels {
+		id := models.AlertID(p.ID, level)
+		alert, exists := m.store.Find(id)
+		breached := level.Breached(p)
+		switch {
+		case breached && exists && alert.Open:
+			if alert.Stock != p.Stock || alert.Threshold != p.ReorderThreshold {
+				alert.Stock, alert.Threshold = p.Stock, p.ReorderThreshold
+				m.store.Upsert(alert)
+			}
+		case breached && exists && time.Since(m.raised[id]) < m.cooldown:
+			alert.Reopen(p)
+			m.store.Upsert(alert)
+			logger.Log.Info(fmt.Sprintf("Alert %s reopened within the cooldown, stock %d", id, p.Stock))
+		case breached:
+			raised := models.NewAlert(p, level)
+			raised.Occurrences += alert.Occurrences
+			m.raised[id] = time.Now()
+			m.store.Upsert(raised, models.NewAlertEvent(raised, p))
+			logger.Log.Warn(fmt.Sprintf("Alert %s raised, stock %d threshold %d", id, p.Stock, p.ReorderThreshold))
+		case exists && alert.Open:
+			alert.Resolve(p)
+			m.store.Upsert(alert)
+			logger.Log.Info(fmt.Sprintf("Alert %s resolved, stock %d", id, p.Stock))
+		}
+	}
+}
+
+// resolveAll resolves the open alerts of a product removed from the catalogue
+func (m *Monitor) resolveAll(p models.Product) {
+	for _, level := range models.AlertLevels {
+		if alert, exists := m.store.Find(models.AlertID(p.ID, level)); exists && alert.Open {
+			alert.Resolve(p)
+			m.store.Upsert(alert)
+		}
+	}
+}
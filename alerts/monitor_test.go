@@ -0,0 +1,101 @@
+package alerts
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("test")
+	os.Exit(m.Run())
+}
+
+func Test_Monitor(t *testing.T) {
+	t.Run("raises when crossing the threshold and running out", func(t *testing.T) {
+		m, outbox := newMonitor(time.Hour)
+		publishStock(m, 1, 6)
+		assert.Empty(t, m.Alerts(false))
+
+		publishStock(m, 2, 4)
+		publishStock(m, 3, 0)
+		alerts := m.Alerts(false)
+		assert.Len(t, alerts, 2)
+		assert.ElementsMatch(t, []models.EventType{models.EventType_StockAlert, models.EventType_StockAlert}, eventTypes(outbox))
+
+		low, _ := m.store.Find(models.AlertID("TEST", models.AlertLevel_LowStock))
+		assert.True(t, low.Open)
+		assert.Equal(t, 0, low.Stock)
+		assert.Equal(t, 5, low.Threshold)
+	})
+
+	t.Run("resolves when the stock recovers", func(t *testing.T) {
+		m, _ := newMonitor(time.Hour)
+		publishStock(m, 1, 4)
+		publishStock(m, 2, 10)
+		assert.Empty(t, m.Alerts(false))
+		resolved := m.Alerts(true)
+		assert.Len(t, resolved, 1)
+		assert.False(t, resolved[0].Open)
+		assert.NotEmpty(t, resolved[0].ResolvedAt)
+	})
+
+	t.Run("deduplicates within the cooldown", func(t *testing.T) {
+		m, outbox := newMonitor(time.Hour)
+		publishStock(m, 1, 4)
+		publishStock(m, 2, 5)
+		publishStock(m, 3, 4)
+		publishStock(m, 4, 5)
+		publishStock(m, 5, 4)
+		// a redelivered event is ignored
+		publishStock(m, 5, 4)
+		assert.Len(t, eventTypes(outbox), 1)
+		alerts := m.Alerts(false)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, 3, alerts[0].Occurrences)
+	})
+
+	t.Run("raises again after the cooldown", func(t *testing.T) {
+		m, outbox := newMonitor(0)
+		publishStock(m, 1, 4)
+		publishStock(m, 2, 5)
+		publishStock(m, 3, 4)
+		assert.Len(t, eventTypes(outbox), 2)
+		assert.Equal(t, 2, m.Alerts(false)[0].Occurrences)
+	})
+
+	t.Run("resolves removed products", func(t *testing.T) {
+		m, _ := newMonitor(time.Hour)
+		publishStock(m, 1, 4)
+		product := models.Product{ID: "TEST", Stock: 4, ReorderThreshold: 5}
+		removed := models.NewProductEvent(models.EventType_ProductRemoved, product)
+		removed.ID = 2
+		assert.Nil(t, m.Publish(removed))
+		assert.Empty(t, m.Alerts(false))
+	})
+}
+
+func newMonitor(cooldown time.Duration) (*Monitor, *db.Outbox) {
+	outbox := db.NewOutbox()
+	return NewMonitor(db.NewAlertDBService(outbox), cooldown), outbox
+}
+
+// publishStock publishes a stock change of the TEST product, which has a reorder threshold of 5
+func publishStock(m *Monitor, id uint64, stock int) {
+	e := models.NewProductEvent(models.EventType_StockChanged, models.Product{ID: "TEST", Stock: stock, ReorderThreshold: 5})
+	e.ID = id
+	_ = m.Publish(e)
+}
+
+func eventTypes(outbox *db.Outbox) []models.EventType {
+	var types []models.EventType
+	for _, e := range outbox.Pending() {
+		types = append(types, e.Type)
+	}
+	return types
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"github.com/orders-app/alerts"
+	"github.com/orders-app/backorders"
+	"github.com/orders-app/catalogue"
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/events"
+	"github.com/orders-app/purchasing"
+	"github.com/orders-app/repo"
+	"github.com/orders-app/reservations"
+	"github.com/orders-app/scheduling"
+	"github.com/orders-app/subscriptions"
+	"github.com/orders-app/webhooks"
+)
+
+// App holds the services shared by all the APIs of the orders app
+type App struct {
+	Repo      repo.Repo
+	Events    *events.Broker
+	Webhooks  *webhooks.Dispatcher
+	Alerts    *alerts.Monitor
+	fulfiller *backorders.Fulfiller
+	watcher   *catalogue.Watcher
+	sweeper   *reservations.Sweeper
+	scheduler *scheduling.Scheduler
+	renewer   *subscriptions.Scheduler
+}
+
+// New creates the repo and relays its outbox to the log, the event broker, the webhooks, the stock alerts
+// and the backorders, stock alerts draft purchase orders when auto drafting is configured.
+// Expired reservations are swept and the orders of due schedules and subscriptions placed when their intervals
+// are configured.
+// The catalogue file is watched for changes when a poll interval is configured.
+func New(cfg config.Config) (*App, error) {
+	outbox := db.NewOutbox()
+	r, err := repo.New(cfg.Queue, cfg.Catalogue.Path, outbox)
+	if err != nil {
+		return nil, err
+	}
+	broker := events.NewBroker(cfg.Events.ReplaySize)
+	dispatcher := webhooks.NewDispatcher(db.NewWebhookDBService(), cfg.Webhooks)
+	monitor := alerts.NewMonitor(db.NewAlertDBService(outbox), cfg.Alerts.Cooldown)
+	fulfiller := backorders.NewFulfiller(r)
+	sinks := []events.Sink{events.LogSink{}, broker, dispatcher, monitor, fulfiller}
+	if cfg.Purchasing.AutoDraft {
+		sinks = append(sinks, purchasing.NewReplenisher(r))
+	}
+	events.NewRelay(outbox, sinks...)
+	a := &App{
+		Repo:      r,
+		Events:    broker,
+		Webhooks:  dispatcher,
+		Alerts:    monitor,
+		fulfiller: fulfiller,
+	}
+	if cfg.Catalogue.PollInterval > 0 {
+		if a.watcher, err = catalogue.NewWatcher(cfg.Catalogue.Path, cfg.Catalogue.PollInterval, r); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Reservations.SweepInterval > 0 {
+		a.sweeper = reservations.NewSweeper(cfg.Reservations.SweepInterval, r)
+	}
+	if cfg.Scheduling.Interval > 0 {
+		a.scheduler = scheduling.NewScheduler(cfg.Scheduling.Interval, r)
+	}
+	if cfg.Subscriptions.Interval > 0 {
+		a.renewer = subscriptions.NewScheduler(cfg.Subscriptions.Interval, r)
+	}
+	return a, nil
+}
+
+// Close stops the background components of the app
+func (a *App) Close() {
+	a.fulfiller.Stop()
+	if a.watcher != nil {
+		a.watcher.Stop()
+	}
+	if a.sweeper != nil {
+		a.sweeper.Stop()
+	}
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+	if a.renewer != nil {
+		a.renewer.Stop()
+	}
+}
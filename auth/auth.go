@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthenticated is returned when a role is required from a request without a principal
+var ErrUnauthenticated = errors.New("authentication required")
+
+// ErrForbidden is returned when the principal does not hold the required role
+var ErrForbidden = errors.New("forbidden")
+
+type Role string
+
+const (
+	Role_Customer Role = "customer"
+	Role_Operator Role = "operator"
+	Role_Admin    Role = "admin"
+)
+
+// roleRanks orders the roles, higher roles are granted everything lower roles are
+var roleRanks = map[Role]int{
+	Role_Customer: 1,
+	Role_Operator: 2,
+	Role_Admin:    3,
+}
+
+// ParseRole validates a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %s", name)
+	}
+	return role, nil
+}
+
+// Allows checks whether the role is granted the required role
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in the context if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authorize checks that the context carries a principal holding at least the required role
+func Authorize(ctx context.Context, required Role) (Principal, error) {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	if !principal.Role.Allows(required) {
+		return principal, fmt.Errorf("%w: role %s is not allowed, requires %s", ErrForbidden, principal.Role, required)
+	}
+	return principal, nil
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RoleAllows(t *testing.T) {
+	assert.True(t, Role_Admin.Allows(Role_Operator))
+	assert.True(t, Role_Admin.Allows(Role_Customer))
+	assert.True(t, Role_Operator.Allows(Role_Operator))
+	assert.True(t, Role_Operator.Allows(Role_Customer))
+	assert.False(t, Role_Operator.Allows(Role_Admin))
+	assert.False(t, Role_Customer.Allows(Role_Operator))
+	// unknown roles are granted nothing
+	assert.False(t, Role("blablabla").Allows(Role_Customer))
+}
+
+func Test_ParseRole(t *testing.T) {
+	role, err := ParseRole("operator")
+	assert.Nil(t, err)
+	assert.Equal(t, Role_Operator, role)
+
+	_, err = ParseRole("blablabla")
+	assert.NotNil(t, err)
+}
+
+func Test_Authorize(t *testing.T) {
+	_, err := Authorize(context.Background(), Role_Customer)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	ctx := WithPrincipal(context.Background(), Principal{Subject: "customer", Role: Role_Customer})
+	principal, err := Authorize(ctx, Role_Customer)
+	assert.Nil(t, err)
+	assert.Equal(t, "customer", principal.Subject)
+
+	_, err = Authorize(ctx, Role_Operator)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/orders-app/config"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+var ErrNoCredentials = errors.New("no credentials provided")
+
+type apiKey struct {
+	name string
+	hash []byte
+	role Role
+}
+
+// Authenticator resolves the principal of a request from its API key or bearer token
+type Authenticator struct {
+	apiKeys   []apiKey
+	jwtSecret []byte
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf,omitempty"`
+}
+
+// NewAuthenticator creates an authenticator accepting the configured credentials
+func NewAuthenticator(cfg config.Auth) (*Authenticator, error) {
+	a := &Authenticator{jwtSecret: []byte(cfg.JWTSecret)}
+	for _, k := range cfg.APIKeys {
+		role, err := ParseRole(k.Role)
+		if err != nil {
+			return nil, fmt.Errorf("api key %s: %v", k.Name, err)
+		}
+		hash, err := hex.DecodeString(k.Hash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %s: hash must be a hex encoded sha256", k.Name)
+		}
+		a.apiKeys = append(a.apiKeys, apiKey{name: k.Name, hash: hash, role: role})
+	}
+	return a, nil
+}
+
+// Credentials are the credentials sent along with a request of any of the APIs
+type Credentials struct {
+	APIKey string
+	// Authorization is the value of the authorization header, e.g. "Bearer <token>"
+	Authorization string
+}
+
+// Authenticate returns the principal of the HTTP request or ErrNoCredentials if none were sent
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return a.AuthenticateCredentials(Credentials{
+		APIKey:        r.Header.Get(apiKeyHeader),
+		Authorization: r.Header.Get("Authorization"),
+	})
+}
+
+// AuthenticateQueryToken returns the principal of the bearer token sent as the access_token query parameter,
+// or ErrNoCredentials if there is none. Browsers can not set headers on WebSocket connections, so only
+// WebSocket upgrades should be authenticated this way.
+func (a *Authenticator) AuthenticateQueryToken(r *http.Request) (Principal, error) {
+	token := r.URL.Query().Get("access_token")
+	if token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	return a.AuthenticateCredentials(Credentials{Authorization: "Bearer " + token})
+}
+
+// AuthenticateCredentials returns the principal the credentials belong to or ErrNoCredentials if they are empty
+func (a *Authenticator) AuthenticateCredentials(creds Credentials) (Principal, error) {
+	if creds.APIKey != "" {
+		return a.authenticateAPIKey(creds.APIKey)
+	}
+	if creds.Authorization != "" {
+		token, ok := strings.CutPrefix(creds.Authorization, "Bearer ")
+		if !ok {
+			return Principal{}, errors.New("unsupported authorization scheme")
+		}
+		return a.authenticateToken(token, time.Now())
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+// authenticateAPIKey compares the hash of the key against all configured keys
+func (a *Authenticator) authenticateAPIKey(key string) (Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash) == 1 {
+			return Principal{Subject: k.name, Role: k.role}, nil
+		}
+	}
+	return Principal{}, errors.New("invalid api key")
+}
+
+// authenticateToken verifies an HS256 signed JWT
+func (a *Authenticator) authenticateToken(token string, now time.Time) (Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return Principal{}, errors.New("bearer tokens are not accepted")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("malformed token header:%v", err)
+	}
+	if header.Alg != "HS256" {
+		return Principal{}, fmt.Errorf("unsupported token algorithm %s", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, errors.New("malformed token signature")
+	}
+	if !hmac.Equal(signature, sign(a.jwtSecret, parts[0]+"."+parts[1])) {
+		return Principal{}, errors.New("invalid token signature")
+	}
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("malformed token claims:%v", err)
+	}
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return Principal{}, errors.New("token expired")
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return Principal{}, errors.New("token not valid yet")
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: claims.Subject, Role: role}, nil
+}
+
+// IssueToken creates an HS256 signed JWT for the principal, mainly useful for tooling and tests
+func (a *Authenticator) IssueToken(p Principal, ttl time.Duration) (string, error) {
+	if len(a.jwtSecret) == 0 {
+		return "", errors.New("no jwt secret configured")
+	}
+	header, err := encodeSegment(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := encodeSegment(jwtClaims{
+		Subject:   p.Subject,
+		Role:      string(p.Role),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + claims
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign(a.jwtSecret, unsigned)), nil
+}
+
+func sign(secret []byte, data string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func encodeSegment(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
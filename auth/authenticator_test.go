@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "secret"
+
+func Test_APIKeys(t *testing.T) {
+	a := newAuthenticator(t)
+
+	t.Run("valid key", func(t *testing.T) {
+		principal, err := a.AuthenticateCredentials(Credentials{APIKey: "customer-key"})
+		assert.Nil(t, err)
+		assert.Equal(t, Principal{Subject: "customer", Role: Role_Customer}, principal)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", "operator-key")
+		principal, err := a.Authenticate(req)
+		assert.Nil(t, err)
+		assert.Equal(t, Role_Operator, principal.Role)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := a.AuthenticateCredentials(Credentials{APIKey: "blablabla"})
+		assert.EqualError(t, err, "invalid api key")
+	})
+
+	t.Run("hash instead of key", func(t *testing.T) {
+		_, err := a.AuthenticateCredentials(Credentials{APIKey: hashKey("customer-key")})
+		assert.EqualError(t, err, "invalid api key")
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		_, err := a.AuthenticateCredentials(Credentials{})
+		assert.ErrorIs(t, err, ErrNoCredentials)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewAuthenticator(config.Auth{APIKeys: []config.APIKey{{Name: "customer", Role: "customer", Hash: "blablabla"}}})
+		assert.NotNil(t, err)
+		_, err = NewAuthenticator(config.Auth{APIKeys: []config.APIKey{{Name: "customer", Role: "blablabla", Hash: hashKey("customer-key")}}})
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_Tokens(t *testing.T) {
+	a := newAuthenticator(t)
+	now := time.Now()
+	claims := jwtClaims{Subject: "customer", Role: string(Role_Customer), ExpiresAt: now.Add(time.Minute).Unix()}
+
+	t.Run("issued token", func(t *testing.T) {
+		token, err := a.IssueToken(Principal{Subject: "operator", Role: Role_Operator}, time.Minute)
+		assert.Nil(t, err)
+		principal, err := a.AuthenticateCredentials(Credentials{Authorization: "Bearer " + token})
+		assert.Nil(t, err)
+		assert.Equal(t, Principal{Subject: "operator", Role: Role_Operator}, principal)
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		principal, err := a.authenticateToken(signToken(t, "HS256", claims, secret), now)
+		assert.Nil(t, err)
+		assert.Equal(t, Principal{Subject: "customer", Role: Role_Customer}, principal)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		_, err := a.authenticateToken(signToken(t, "HS256", claims, "other-secret"), now)
+		assert.EqualError(t, err, "invalid token signature")
+	})
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token := signToken(t, "HS256", claims, secret)
+		admin := claims
+		admin.Role = string(Role_Admin)
+		forged := signToken(t, "HS256", admin, secret)
+		parts, forgedParts := strings.Split(token, "."), strings.Split(forged, ".")
+		_, err := a.authenticateToken(parts[0]+"."+forgedParts[1]+"."+parts[2], now)
+		assert.EqualError(t, err, "invalid token signature")
+	})
+
+	t.Run("alg none", func(t *testing.T) {
+		parts := strings.Split(signToken(t, "none", claims, secret), ".")
+		_, err := a.authenticateToken(parts[0]+"."+parts[1]+".", now)
+		assert.EqualError(t, err, "unsupported token algorithm none")
+	})
+
+	t.Run("alg confusion", func(t *testing.T) {
+		// a token signed with the secret but claiming another algorithm is rejected before its signature is checked
+		_, err := a.authenticateToken(signToken(t, "HS512", claims, secret), now)
+		assert.EqualError(t, err, "unsupported token algorithm HS512")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := a.authenticateToken(signToken(t, "HS256", claims, secret), now.Add(time.Minute))
+		assert.EqualError(t, err, "token expired")
+
+		noExpiry := claims
+		noExpiry.ExpiresAt = 0
+		_, err = a.authenticateToken(signToken(t, "HS256", noExpiry, secret), now)
+		assert.EqualError(t, err, "token expired")
+	})
+
+	t.Run("not valid yet", func(t *testing.T) {
+		early := claims
+		early.NotBefore = now.Add(30 * time.Second).Unix()
+		_, err := a.authenticateToken(signToken(t, "HS256", early, secret), now)
+		assert.EqualError(t, err, "token not valid yet")
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		unknown := claims
+		unknown.Role = "blablabla"
+		_, err := a.authenticateToken(signToken(t, "HS256", unknown, secret), now)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := a.authenticateToken("blablabla", now)
+		assert.EqualError(t, err, "malformed token")
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := a.AuthenticateCredentials(Credentials{Authorization: "Basic " + signToken(t, "HS256", claims, secret)})
+		assert.EqualError(t, err, "unsupported authorization scheme")
+	})
+
+	t.Run("no secret configured", func(t *testing.T) {
+		a, err := NewAuthenticator(config.Auth{})
+		assert.Nil(t, err)
+		_, err = a.authenticateToken(signToken(t, "HS256", claims, ""), now)
+		assert.EqualError(t, err, "bearer tokens are not accepted")
+	})
+}
+
+func newAuthenticator(t *testing.T) *Authenticator {
+	a, err := NewAuthenticator(config.Auth{
+		APIKeys: []config.APIKey{
+			{Name: "customer", Role: string(Role_Customer), Hash: hashKey("customer-key")},
+			{Name: "operator", Role: string(Role_Operator), Hash: hashKey("operator-key")},
+		},
+		JWTSecret: secret,
+	})
+	assert.Nil(t, err)
+	return a
+}
+
+// signToken creates a JWT with the given algorithm in its header, always signed with HMAC-SHA256
+func signToken(t *testing.T, alg string, claims jwtClaims, secret string) string {
+	header, err := encodeSegment(jwtHeader{Alg: alg, Typ: "JWT"})
+	assert.Nil(t, err)
+	payload, err := encodeSegment(claims)
+	assert.Nil(t, err)
+	unsigned := header + "." + payload
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(secret), unsigned))
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,98 @@
+package backorders
+
+import (
+	"sync"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// Fulfiller is an event sink placing the backorders of a product back on the intake queue when its stock changes.
+// A single worker fulfills the products, several changes of a product waiting for it are fulfilled once.
+type Fulfiller struct {
+	repo repo.Repo
+	// lastID is the id of the last event received, events are delivered at least once
+	lastID uint64
+	// pending holds the products waiting for the worker in the order they changed, queued their ids
+	pending  []string
+	queued   map[string]bool
+	lock     sync.Mutex
+	wake     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFulfiller creates a fulfiller of the backorders in the repo and starts its worker
+func NewFulfiller(r repo.Repo) *Fulfiller {
+	f := &Fulfiller{
+		repo:   r,
+		queued: make(map[string]bool),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go f.fulfill()
+	return f
+}
+
+func (f *Fulfiller) Name() string {
+	return "backorders"
+}
+
+// Stop stops fulfilling backorders
+func (f *Fulfiller) Stop() {
+	f.stopOnce.Do(func() { close(f.done) })
+}
+
+// Publish queues the product of a stock change for the worker
+func (f *Fulfiller) Publish(e models.Event) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if e.ID <= f.lastID {
+		return nil
+	}
+	f.lastID = e.ID
+	switch e.Type {
+	case models.EventType_StockChanged, models.EventType_ProductUpdated:
+		// placing the orders waits for room on the intake queue, which must not hold up the other sinks
+		if !f.queued[e.ProductID] {
+			f.queued[e.ProductID] = true
+			f.pending = append(f.pending, e.ProductID)
+		}
+		select {
+		case f.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// fulfill fulfills the backorders of the pending products until the fulfiller is stopped
+func (f *Fulfiller) fulfill() {
+	for {
+		select {
+		case <-f.wake:
+		case <-f.done:
+			return
+		}
+		for {
+			productID, ok := f.next()
+			if !ok {
+				break
+			}
+			f.repo.FulfillBackorders(productID)
+		}
+	}
+}
+
+// next takes the product which changed first off the pending products
+func (f *Fulfiller) next() (string, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.pending) == 0 {
+		return "", false
+	}
+	productID := f.pending[0]
+	f.pending = f.pending[1:]
+	delete(f.queued, productID)
+	return productID, true
+}
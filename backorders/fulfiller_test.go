@@ -0,0 +1,63 @@
+package backorders_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-app/backorders"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingRepo records the fulfilled products, the first fulfillment waits until it is released
+type blockingRepo struct {
+	repo.Repo
+	release   chan struct{}
+	fulfilled []string
+	lock      sync.Mutex
+}
+
+func (b *blockingRepo) FulfillBackorders(productID string) {
+	b.lock.Lock()
+	first := len(b.fulfilled) == 0
+	b.fulfilled = append(b.fulfilled, productID)
+	b.lock.Unlock()
+	if first {
+		<-b.release
+	}
+}
+
+func (b *blockingRepo) snapshot() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]string(nil), b.fulfilled...)
+}
+
+func Test_Fulfiller(t *testing.T) {
+	r := &blockingRepo{release: make(chan struct{})}
+	f := backorders.NewFulfiller(r)
+	defer f.Stop()
+
+	assert.Nil(t, f.Publish(newEvent(1, "MWBLU")))
+	assert.Eventually(t, func() bool { return len(r.snapshot()) == 1 }, time.Second, time.Millisecond)
+
+	// the changes made while the worker is busy are fulfilled once per product
+	assert.Nil(t, f.Publish(newEvent(2, "MWLEM")))
+	assert.Nil(t, f.Publish(newEvent(3, "MWBLU")))
+	assert.Nil(t, f.Publish(newEvent(4, "MWLEM")))
+	// a relayed duplicate is ignored
+	assert.Nil(t, f.Publish(newEvent(4, "MWORG")))
+	close(r.release)
+
+	assert.Eventually(t, func() bool { return len(r.snapshot()) == 3 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []string{"MWBLU", "MWLEM", "MWBLU"}, r.snapshot())
+}
+
+func newEvent(id uint64, productID string) models.Event {
+	e := models.NewProductEvent(models.EventType_StockChanged, models.Product{ID: productID})
+	e.ID = id
+	return e
+}
@@ -0,0 +1,151 @@
+package catalogue
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/orders-app/utils"
+)
+
+// Watcher polls the catalogue file and applies its changes to the products of the repo.
+// Polling works on every file system, unlike change notifications.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	repo     repo.Repo
+	// listed holds the products as last read from the file
+	listed   map[string]models.Product
+	modTime  time.Time
+	size     int64
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Summary counts the changes of a reload
+type Summary struct {
+	Created  int
+	Updated  int
+	Unlisted int
+}
+
+// NewWatcher reads the current catalogue file and starts checking it for changes every interval
+func NewWatcher(path string, interval time.Duration, r repo.Repo) (*Watcher, error) {
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		repo:     r,
+		done:     make(chan struct{}),
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	products, err := utils.ImportProducts(path)
+	if err != nil {
+		return nil, err
+	}
+	w.listed = byID(products)
+	w.modTime, w.size = info.ModTime(), info.Size()
+	go w.watch()
+	return w, nil
+}
+
+// Stop stops watching the file
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) watch() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// check reloads the file when its modification time or size changed
+func (w *Watcher) check() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		logger.Log.Warn(fmt.Sprintf("Checking catalogue %s failed: %v", w.path, err))
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+	products, err := utils.ImportProducts(w.path)
+	// remember the version even if it is invalid, so it is only reported once
+	w.modTime, w.size = info.ModTime(), info.Size()
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Reloading catalogue %s failed, keeping the current products: %v", w.path, err))
+		return
+	}
+	summary, err := w.reload(products)
+	if err != nil {
+		// the changes applied so far are remembered, the others are retried with the next check
+		w.modTime, w.size = time.Time{}, 0
+		logger.Log.Error(fmt.Sprintf("Applying catalogue %s failed, retrying with the next check: %v", w.path, err))
+		return
+	}
+	logger.Log.Info(fmt.Sprintf("Catalogue %s reloaded: %d created, %d updated, %d no longer listed and kept",
+		w.path, summary.Created, summary.Updated, summary.Unlisted))
+}
+
+// reload applies the differences between the products read from the file and the last version it applied,
+// every applied change is remembered at once so a failed reload does not apply it twice
+func (w *Watcher) reload(products []models.Product) (Summary, error) {
+	var summary Summary
+	for _, change := range diff(w.listed, products) {
+		if _, err := w.repo.ApplyCatalogueChange(change); err != nil {
+			return summary, err
+		}
+		w.listed[change.Product.ID] = change.Product
+		if change.Created {
+			summary.Created++
+		} else {
+			summary.Updated++
+		}
+	}
+	listed := byID(products)
+	for id := range w.listed {
+		if _, ok := listed[id]; !ok {
+			summary.Unlisted++
+		}
+	}
+	w.listed = listed
+	return summary, nil
+}
+
+// diff returns the changes between two versions of the file, products removed from the file are kept in the app
+func diff(listed map[string]models.Product, products []models.Product) []models.CatalogueChange {
+	var changes []models.CatalogueChange
+	for _, p := range products {
+		previous, ok := listed[p.ID]
+		if !ok {
+			changes = append(changes, models.CatalogueChange{Product: p, Created: true})
+			continue
+		}
+		if !previous.Equal(p) {
+			changes = append(changes, models.CatalogueChange{Product: p, StockDelta: p.Stock - previous.Stock})
+		}
+	}
+	return changes
+}
+
+func byID(products []models.Product) map[string]models.Product {
+	m := make(map[string]models.Product, len(products))
+	for _, p := range products {
+		m[p.ID] = p
+	}
+	return m
+}
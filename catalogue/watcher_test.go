@@ -0,0 +1,120 @@
+package catalogue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+const header = "ID,ProductName,Stock,Variety,Price\n"
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("test")
+	os.Exit(m.Run())
+}
+
+func Test_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv")
+	writeCatalogue(t, path, header+"MWBLU,Mineral Water,20,Blueberry,1.79\nMWLEM,Mineral Water,30,Lemon-Lime,1.39\n")
+	r, err := repo.New(config.Queue{Capacity: 10, EnqueueTimeout: time.Second}, path, db.NewOutbox())
+	assert.Nil(t, err)
+	w, err := NewWatcher(path, time.Hour, r)
+	assert.Nil(t, err)
+	defer w.Stop()
+
+	// consume some stock before the file changes
+	order, err := r.CreateOrder(context.Background(), models.Item{ProductID: "MWBLU", Amount: 5})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	processed, err := r.WaitForOrder(ctx, order.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, string(models.OrderStatus_Completed), processed.Status)
+
+	t.Run("restock, price change and new product", func(t *testing.T) {
+		writeCatalogue(t, path, header+"MWBLU,Mineral Water,30,Blueberry,1.99\nMWORG,Mineral Water,10,Orange,1.49\n")
+		w.check()
+
+		blueberry, err := r.GetProduct("MWBLU")
+		assert.Nil(t, err)
+		assert.Equal(t, 25, blueberry.Stock)
+		assert.Equal(t, 1.99, blueberry.Price)
+		orange, err := r.GetProduct("MWORG")
+		assert.Nil(t, err)
+		assert.Equal(t, 10, orange.Stock)
+		// products no longer listed are kept
+		_, err = r.GetProduct("MWLEM")
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid file is ignored", func(t *testing.T) {
+		writeCatalogue(t, path, header+"MWBLU,Mineral Water,many,Blueberry,1.99\n")
+		w.check()
+
+		blueberry, err := r.GetProduct("MWBLU")
+		assert.Nil(t, err)
+		assert.Equal(t, 25, blueberry.Stock)
+	})
+}
+
+// failingRepo fails to apply the catalogue changes of one product
+type failingRepo struct {
+	repo.Repo
+	failID string
+}
+
+func (f *failingRepo) ApplyCatalogueChange(change models.CatalogueChange) (models.Product, error) {
+	if change.Product.ID == f.failID {
+		return models.Product{}, errors.New("blablabla")
+	}
+	return f.Repo.ApplyCatalogueChange(change)
+}
+
+func Test_PartialReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv")
+	writeCatalogue(t, path, header+"MWBLU,Mineral Water,20,Blueberry,1.79\nMWLEM,Mineral Water,30,Lemon-Lime,1.39\n")
+	r, err := repo.New(config.Queue{Capacity: 10, EnqueueTimeout: time.Second}, path, db.NewOutbox())
+	assert.Nil(t, err)
+	failing := &failingRepo{Repo: r, failID: "MWLEM"}
+	w, err := NewWatcher(path, time.Hour, failing)
+	assert.Nil(t, err)
+	defer w.Stop()
+
+	writeCatalogue(t, path, header+"MWBLU,Mineral Water,30,Blueberry,1.79\nMWLEM,Mineral Water,40,Lemon-Lime,1.39\n")
+	w.check()
+	blueberry, err := r.GetProduct("MWBLU")
+	assert.Nil(t, err)
+	assert.Equal(t, 30, blueberry.Stock)
+
+	// the next check retries the failed change without restocking the applied one again
+	failing.failID = ""
+	w.check()
+	blueberry, err = r.GetProduct("MWBLU")
+	assert.Nil(t, err)
+	assert.Equal(t, 30, blueberry.Stock)
+	lemon, err := r.GetProduct("MWLEM")
+	assert.Nil(t, err)
+	assert.Equal(t, 40, lemon.Stock)
+}
+
+// writeCatalogue writes the file and moves its modification time forward,
+// so writes quicker than the file system time resolution are noticed
+func writeCatalogue(t *testing.T, path, content string) {
+	previous := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		previous = info.ModTime()
+	}
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	later := previous.Add(time.Second)
+	assert.Nil(t, os.Chtimes(path, later, later))
+}
@@ -3,17 +3,22 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
-	"sync"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/orders-app/models"
 )
 
 const simulationCount int = 50
-const ordersEndpoint string = "http://localhost:3000/orders"
+
+// batchSize fits the default ORDERS_BATCH_MAX_SIZE and the burst of the default orders rate limit
+const batchSize int = 10
+const batchEndpoint string = "http://localhost:3000/v1/orders/batch"
 const indexEndpoint string = "http://localhost:3000/"
 const maxOrderAmount int = 15
 
@@ -26,42 +31,77 @@ func main() {
 	if err := checkIndex(); err != nil {
 		log.Fatalf("Endpoint %s is not up. Please start the server before running simulations.", indexEndpoint)
 	}
-	var wg sync.WaitGroup
-	wg.Add(simulationCount)
-	for i := 0; i < simulationCount; i++ {
-		go createRandomOrder(i, &wg)
+	items := make([]models.Item, simulationCount)
+	for i := range items {
+		items[i] = randomItem()
+	}
+	for start := 0; start < len(items); start += batchSize {
+		end := min(start+batchSize, len(items))
+		if err := sendBatch(items[start:end], start); err != nil {
+			log.Fatal(err)
+		}
 	}
-	wg.Wait()
 }
 
-func createRandomOrder(number int, wg *sync.WaitGroup) {
-	defer wg.Done()
-	rand.Seed(time.Now().UnixNano())
-	amount := rand.Intn(maxOrderAmount) + 1
-	product := products[rand.Intn(len(products))]
-	item := models.Item{
-		ProductID: product,
-		Amount:    amount,
+func randomItem() models.Item {
+	return models.Item{
+		ProductID: products[rand.Intn(len(products))],
+		Amount:    rand.Intn(maxOrderAmount) + 1,
 	}
-	log.Printf("[simulation-%d]: sending order %+v", number, item)
+}
 
-	ibytes, err := json.Marshal(item)
-	if err != nil {
-		log.Fatal(err)
-	}
-	req, err := http.NewRequest("POST", ordersEndpoint, bytes.NewBuffer(ibytes))
+// sendBatch places the orders in a single batch request and logs the outcome of each of them,
+// offset is the number of the first simulated order. Rate limited batches are sent again once allowed.
+func sendBatch(items []models.Item, offset int) error {
+	ibytes, err := json.Marshal(map[string][]models.Item{"items": items})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	var resp *http.Response
+	for {
+		req, err := http.NewRequest("POST", batchEndpoint, bytes.NewBuffer(ibytes))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", os.Getenv("ORDERS_API_KEY"))
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		log.Fatal(err)
+		client := &http.Client{}
+		resp, err = client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			break
+		}
+		resp.Body.Close()
+		wait, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil {
+			wait = 1
+		}
+		log.Printf("[simulation-%d]: rate limited, retrying in %ds", offset, wait)
+		time.Sleep(time.Duration(wait) * time.Second)
 	}
+	defer resp.Body.Close()
 
-	log.Printf("[simulation-%d]: completed", number)
+	var body struct {
+		Data  []models.BatchEntry `json:"data"`
+		Error string              `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	if body.Error != "" {
+		return fmt.Errorf("batch failed with status %d:%s", resp.StatusCode, body.Error)
+	}
+	for i, entry := range body.Data {
+		if entry.Order != nil {
+			log.Printf("[simulation-%d]: order %s accepted for %+v", offset+i, entry.Order.ID, items[i])
+		} else {
+			log.Printf("[simulation-%d]: order %+v not placed:%s", offset+i, items[i], entry.Error)
+		}
+	}
+	return nil
 }
 
 func checkIndex() error {
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRateLimit is the name of the rate limit policy used by routes without their own policy
+const DefaultRateLimit = "default"
+
+// OrdersRateLimit is the name of the rate limit policy charged for every order placed
+const OrdersRateLimit = "orders"
+
+// Config holds the runtime configuration of the orders app
+type Config struct {
+	Port          string
+	GrpcPort      string
+	Auth          Auth
+	RateLimits    map[string]RateLimit
+	Queue         Queue
+	Events        Events
+	Webhooks      Webhooks
+	Catalogue     Catalogue
+	Alerts        Alerts
+	Purchasing    Purchasing
+	Reservations  Reservations
+	Scheduling    Scheduling
+	Subscriptions Subscriptions
+}
+
+// Subscriptions configures the recurring subscription orders
+type Subscriptions struct {
+	// Interval is how often the orders of due subscriptions are placed, zero pauses all subscriptions
+	Interval time.Duration
+}
+
+// Scheduling configures the orders scheduled for a later time
+type Scheduling struct {
+	// Interval is how often due orders are placed, zero keeps scheduled orders waiting
+	Interval time.Duration
+}
+
+// Reservations configures the stock reservations
+type Reservations struct {
+	// SweepInterval is how often expired reservations return their stock, zero disables expiry
+	SweepInterval time.Duration
+}
+
+// Purchasing configures the replenishment of products from suppliers
+type Purchasing struct {
+	// AutoDraft drafts a purchase order whenever a stock alert is raised for a product
+	AutoDraft bool
+}
+
+// Alerts configures the stock alerts
+type Alerts struct {
+	// Cooldown is how long after an alert was raised it is reopened without being raised again
+	Cooldown time.Duration
+}
+
+// Catalogue configures the product catalogue file
+type Catalogue struct {
+	Path string
+	// PollInterval is how often the file is checked for changes, zero disables reloading
+	PollInterval time.Duration
+}
+
+// Auth holds the credentials accepted by the app
+type Auth struct {
+	APIKeys   []APIKey
+	JWTSecret string
+}
+
+// APIKey is a hashed API key together with the role it grants
+type APIKey struct {
+	Name string
+	// Hash is the hex encoded SHA-256 hash of the key
+	Hash string
+	Role string
+}
+
+// RateLimit configures a token bucket refilling Rate tokens per second up to Burst tokens
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Queue configures the intake queue orders wait in until they are processed
+type Queue struct {
+	Capacity       int
+	EnqueueTimeout time.Duration
+	// MaxBatchSize is the most orders accepted in a single batch
+	MaxBatchSize int
+}
+
+// Events configures the event stream
+type Events struct {
+	// ReplaySize is how many of the latest events are kept for clients resuming the stream
+	ReplaySize int
+	Heartbeat  time.Duration
+}
+
+// Webhooks configures the delivery of webhooks
+type Webhooks struct {
+	Workers     int
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it doubles with every further retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	// History is how many succeeded and dead deliveries are kept, the oldest ones are removed first
+	History int
+}
+
+// Load reads the configuration from the environment
+func Load() (Config, error) {
+	keys, err := parseAPIKeys(os.Getenv("ORDERS_API_KEYS"))
+	if err != nil {
+		return Config{}, err
+	}
+	limits, err := parseRateLimits(getEnv("ORDERS_RATE_LIMITS", "default=20:40,orders=5:10"))
+	if err != nil {
+		return Config{}, err
+	}
+	capacity, err := strconv.Atoi(getEnv("ORDERS_QUEUE_CAPACITY", "100"))
+	if err != nil || capacity < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_QUEUE_CAPACITY, want a non negative number")
+	}
+	timeout, err := time.ParseDuration(getEnv("ORDERS_ENQUEUE_TIMEOUT", "500ms"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ORDERS_ENQUEUE_TIMEOUT:%v", err)
+	}
+	maxBatchSize, err := strconv.Atoi(getEnv("ORDERS_BATCH_MAX_SIZE", "10"))
+	if err != nil || maxBatchSize < 1 {
+		return Config{}, fmt.Errorf("invalid ORDERS_BATCH_MAX_SIZE, want a positive number")
+	}
+	// every item of a batch is charged to the orders policy, so a larger batch could never be afforded
+	ordersLimit, ok := limits[OrdersRateLimit]
+	if !ok {
+		ordersLimit = limits[DefaultRateLimit]
+	}
+	if maxBatchSize > ordersLimit.Burst {
+		return Config{}, fmt.Errorf("invalid ORDERS_BATCH_MAX_SIZE, want at most the burst %d of the %s rate limit policy", ordersLimit.Burst, OrdersRateLimit)
+	}
+	replaySize, err := strconv.Atoi(getEnv("ORDERS_EVENTS_REPLAY_SIZE", "1000"))
+	if err != nil || replaySize < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_EVENTS_REPLAY_SIZE, want a non negative number")
+	}
+	heartbeat, err := time.ParseDuration(getEnv("ORDERS_EVENTS_HEARTBEAT", "15s"))
+	if err != nil || heartbeat <= 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_EVENTS_HEARTBEAT, want a positive duration")
+	}
+	maxAttempts, err := strconv.Atoi(getEnv("ORDERS_WEBHOOK_MAX_ATTEMPTS", "8"))
+	if err != nil || maxAttempts < 1 {
+		return Config{}, fmt.Errorf("invalid ORDERS_WEBHOOK_MAX_ATTEMPTS, want a positive number")
+	}
+	history, err := strconv.Atoi(getEnv("ORDERS_WEBHOOK_HISTORY", "10000"))
+	if err != nil || history < 1 {
+		return Config{}, fmt.Errorf("invalid ORDERS_WEBHOOK_HISTORY, want a positive number")
+	}
+	pollInterval, err := time.ParseDuration(getEnv("ORDERS_CATALOGUE_POLL", "5s"))
+	if err != nil || pollInterval < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_CATALOGUE_POLL, want a non negative duration")
+	}
+	cooldown, err := time.ParseDuration(getEnv("ORDERS_ALERT_COOLDOWN", "15m"))
+	if err != nil || cooldown < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_ALERT_COOLDOWN, want a non negative duration")
+	}
+	autoDraft, err := strconv.ParseBool(getEnv("ORDERS_PURCHASING_AUTO_DRAFT", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ORDERS_PURCHASING_AUTO_DRAFT, want true or false")
+	}
+	sweepInterval, err := time.ParseDuration(getEnv("ORDERS_RESERVATION_SWEEP", "1s"))
+	if err != nil || sweepInterval < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_RESERVATION_SWEEP, want a non negative duration")
+	}
+	scheduleInterval, err := time.ParseDuration(getEnv("ORDERS_SCHEDULE_INTERVAL", "1s"))
+	if err != nil || scheduleInterval < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_SCHEDULE_INTERVAL, want a non negative duration")
+	}
+	subscriptionInterval, err := time.ParseDuration(getEnv("ORDERS_SUBSCRIPTION_INTERVAL", "1s"))
+	if err != nil || subscriptionInterval < 0 {
+		return Config{}, fmt.Errorf("invalid ORDERS_SUBSCRIPTION_INTERVAL, want a non negative duration")
+	}
+	return Config{
+		Port:     getEnv("PORT", "3000"),
+		GrpcPort: getEnv("GRPC_PORT", "50051"),
+		Auth: Auth{
+			APIKeys:   keys,
+			JWTSecret: os.Getenv("ORDERS_JWT_SECRET"),
+		},
+		RateLimits: limits,
+		Queue: Queue{
+			Capacity:       capacity,
+			EnqueueTimeout: timeout,
+			MaxBatchSize:   maxBatchSize,
+		},
+		Events: Events{
+			ReplaySize: replaySize,
+			Heartbeat:  heartbeat,
+		},
+		Webhooks: Webhooks{
+			Workers:        4,
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Timeout:        5 * time.Second,
+			History:        history,
+		},
+		Catalogue: Catalogue{
+			Path:         getEnv("ORDERS_CATALOGUE_PATH", "./input/products.csv"),
+			PollInterval: pollInterval,
+		},
+		Alerts: Alerts{
+			Cooldown: cooldown,
+		},
+		Purchasing: Purchasing{
+			AutoDraft: autoDraft,
+		},
+		Reservations: Reservations{
+			SweepInterval: sweepInterval,
+		},
+		Scheduling: Scheduling{
+			Interval: scheduleInterval,
+		},
+		Subscriptions: Subscriptions{
+			Interval: subscriptionInterval,
+		},
+	}, nil
+}
+
+// parseAPIKeys parses a comma separated list of name:role:sha256 entries
+func parseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid api key entry %q, want name:role:sha256", entry)
+		}
+		keys = append(keys, APIKey{
+			Name: parts[0],
+			Role: parts[1],
+			Hash: strings.ToLower(parts[2]),
+		})
+	}
+	return keys, nil
+}
+
+// parseRateLimits parses a comma separated list of policy=rate:burst entries
+func parseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		rate, burst, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid rate limit entry %q, want policy=rate:burst", entry)
+		}
+		r, err := strconv.ParseFloat(rate, 64)
+		if err != nil || r <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit entry %q", entry)
+		}
+		b, err := strconv.Atoi(burst)
+		if err != nil || b < 1 {
+			return nil, fmt.Errorf("invalid burst in rate limit entry %q", entry)
+		}
+		limits[name] = RateLimit{Rate: r, Burst: b}
+	}
+	if _, ok := limits[DefaultRateLimit]; !ok {
+		return nil, fmt.Errorf("missing %s rate limit policy", DefaultRateLimit)
+	}
+	return limits, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// AlertDB stores the stock alerts, one per product and level
+type AlertDB struct {
+	alerts sync.Map
+	outbox *Outbox
+}
+
+// NewAlertDBService creates a new empty alert service writing its events to the outbox
+func NewAlertDBService(outbox *Outbox) *AlertDB {
+	return &AlertDB{outbox: outbox}
+}
+
+// Find returns an alert if exists
+func (a *AlertDB) Find(id string) (models.Alert, bool) {
+	alert, ok := a.alerts.Load(id)
+	if !ok {
+		return models.Alert{}, false
+	}
+	return alert.(models.Alert), true
+}
+
+// Upsert creates or updates an alert together with the events of the change
+func (a *AlertDB) Upsert(alert models.Alert, events ...models.Event) {
+	a.outbox.Write(func() {
+		a.alerts.Store(alert.ID, alert)
+	}, events...)
+}
+
+// GetAll lists all alerts, the latest raised first
+func (a *AlertDB) GetAll() []models.Alert {
+	var all []models.Alert
+	a.alerts.Range(func(key, value any) bool {
+		all = append(all, value.(models.Alert))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].RaisedAt != all[j].RaisedAt {
+			return all[i].RaisedAt > all[j].RaisedAt
+		}
+		return all[i].ID < all[j].ID
+	})
+	return all
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// backorder is an order waiting in the queue of its product
+type backorder struct {
+	orderID string
+	amount  int
+	// dispatched is set while the order is on its way through the intake queue
+	dispatched bool
+}
+
+// BackorderDB holds the backordered orders of each product in the order they were backordered
+type BackorderDB struct {
+	queues map[string][]backorder
+	lock   sync.Mutex
+}
+
+// NewBackorderDBService creates a new empty backorder service
+func NewBackorderDBService() *BackorderDB {
+	return &BackorderDB{queues: make(map[string][]backorder)}
+}
+
+// Push appends an order to the queue of its product
+func (d *BackorderDB) Push(order models.Order) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	id := order.Item.ProductID
+	d.queues[id] = append(d.queues[id], backorder{orderID: order.ID, amount: order.Item.Amount})
+}
+
+// Waiting returns how many orders wait in the queue of a product
+func (d *BackorderDB) Waiting(productID string) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.queues[productID])
+}
+
+// Dispatch marks the orders at the front of the queue of a product which fit into the available stock as dispatched
+// and returns their ids. The stock is reduced by the orders already dispatched, and an order which does not fit
+// stops the dispatch so no order overtakes an earlier one.
+func (d *BackorderDB) Dispatch(productID string, available int) []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	queue := d.queues[productID]
+	for _, b := range queue {
+		if b.dispatched {
+			available -= b.amount
+		}
+	}
+	var ids []string
+	for i, b := range queue {
+		if b.dispatched {
+			continue
+		}
+		if b.amount > available {
+			break
+		}
+		queue[i].dispatched = true
+		available -= b.amount
+		ids = append(ids, b.orderID)
+	}
+	return ids
+}
+
+// Done takes a dispatched order off the queue of a product once it is no longer backordered,
+// otherwise it waits at its place in the queue again
+func (d *BackorderDB) Done(productID, orderID string, backordered bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	queue := d.queues[productID]
+	i := slices.IndexFunc(queue, func(b backorder) bool { return b.orderID == orderID })
+	switch {
+	case i < 0:
+	case backordered:
+		queue[i].dispatched = false
+	default:
+		d.queues[productID] = slices.Delete(queue, i, i+1)
+	}
+}
+
+// ProductIDs lists the products with waiting orders
+func (d *BackorderDB) ProductIDs() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var ids []string
+	for id, queue := range d.queues {
+		if len(queue) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/orders-app/models"
@@ -9,11 +10,15 @@ import (
 
 type OrderDB struct {
 	orders sync.Map
+	// returns holds the returns of each order in the order they were made, guarded by returnsLock
+	returns     map[string][]models.Return
+	returnsLock sync.Mutex
+	outbox      *Outbox
 }
 
-// NewOrderDBService creates new order db service
-func NewOrderDBService() *OrderDB {
-	return &OrderDB{}
+// NewOrderDBService creates new order db service writing its events to the outbox
+func NewOrderDBService(outbox *Outbox) *OrderDB {
+	return &OrderDB{outbox: outbox, returns: make(map[string][]models.Return)}
 }
 
 // Find order for a given order id
@@ -25,9 +30,107 @@ func (o *OrderDB) Find(id string) (models.Order, error) {
 	return toOrder(order), nil
 }
 
-// Upsert creates or updates an order in the orders database
-func (o *OrderDB) Upsert(order models.Order) {
-	o.orders.Store(order.ID, order)
+// Upsert creates or updates an order in the orders database together with the events of the change
+func (o *OrderDB) Upsert(order models.Order, events ...models.Event) {
+	o.outbox.Write(func() {
+		o.orders.Store(order.ID, order)
+	}, events...)
+}
+
+// UpsertAll creates or updates several orders in one storage operation together with the events of the change
+func (o *OrderDB) UpsertAll(orders []models.Order, events ...models.Event) {
+	o.outbox.Write(func() {
+		for _, order := range orders {
+			o.orders.Store(order.ID, order)
+		}
+	}, events...)
+}
+
+// CompareAndSwap updates an order only if it is still in the old state, together with the events of the change
+func (o *OrderDB) CompareAndSwap(old, updated models.Order, events ...models.Event) bool {
+	return o.outbox.WriteIf(func() bool {
+		return o.orders.CompareAndSwap(old.ID, old, updated)
+	}, events...)
+}
+
+// CompareAndSwapStock updates an order and the stock of products in one storage operation together with the events
+// of the change, nothing is changed unless the order and the products are still in their old state
+func (o *OrderDB) CompareAndSwapStock(products *ProductDB, old, updated models.Order, stock []ProductSwap, events ...models.Event) bool {
+	return o.outbox.WriteIf(func() bool {
+		products.writeLock.Lock()
+		defer products.writeLock.Unlock()
+		current, ok := o.orders.Load(old.ID)
+		if !ok || toOrder(current) != old || !products.swap(stock...) {
+			return false
+		}
+		o.orders.Store(updated.ID, updated)
+		return true
+	}, events...)
+}
+
+// Return updates an order, restocks its product and records the return in one storage operation together with
+// the events of the change, nothing is changed unless the order and the product are still in their old state.
+// Without a stock change only the order is updated, e.g. when its product was removed.
+func (o *OrderDB) Return(products *ProductDB, old, updated models.Order, stock []ProductSwap, ret models.Return, events ...models.Event) bool {
+	return o.outbox.WriteIf(func() bool {
+		products.writeLock.Lock()
+		defer products.writeLock.Unlock()
+		current, ok := o.orders.Load(old.ID)
+		if !ok || toOrder(current) != old || !products.swap(stock...) {
+			return false
+		}
+		o.orders.Store(updated.ID, updated)
+		o.returnsLock.Lock()
+		o.returns[updated.ID] = append(o.returns[updated.ID], ret)
+		o.returnsLock.Unlock()
+		return true
+	}, events...)
+}
+
+// GetReturns lists the returns of an order in the order they were made
+func (o *OrderDB) GetReturns(id string) []models.Return {
+	o.returnsLock.Lock()
+	defer o.returnsLock.Unlock()
+	return append([]models.Return{}, o.returns[id]...)
+}
+
+// Delete removes an order from the orders database together with the events of the change
+func (o *OrderDB) Delete(id string, events ...models.Event) {
+	o.outbox.Write(func() {
+		o.orders.Delete(id)
+	}, events...)
+}
+
+// DeleteAll removes several orders in one storage operation together with the events of the change
+func (o *OrderDB) DeleteAll(ids []string, events ...models.Event) {
+	o.outbox.Write(func() {
+		for _, id := range ids {
+			o.orders.Delete(id)
+		}
+	}, events...)
+}
+
+// GetAll lists all orders in the database ordered by creation time
+func (o *OrderDB) GetAll() []models.Order {
+	var allOrders []models.Order
+	o.orders.Range(func(key, value any) bool {
+		allOrders = append(allOrders, toOrder(value))
+		return true
+	})
+	sort.Slice(allOrders, func(i, j int) bool {
+		if allOrders[i].CreatedAt == allOrders[j].CreatedAt {
+			return allOrders[i].ID < allOrders[j].ID
+		}
+		return allOrders[i].CreatedAt < allOrders[j].CreatedAt
+	})
+	return allOrders
+}
+
+// Range calls visit for every order in no particular order, it stops as soon as visit returns false
+func (o *OrderDB) Range(visit func(models.Order) bool) {
+	o.orders.Range(func(key, value any) bool {
+		return visit(toOrder(value))
+	})
 }
 
 func toOrder(o any) models.Order {
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// Outbox holds the domain events which have not been relayed to all sinks yet.
+// Events are written together with the state change they describe, so no event
+// is ever relayed for state which was not stored and no stored state misses its event.
+type Outbox struct {
+	events []models.Event
+	lastID uint64
+	notify chan struct{}
+	lock   sync.Mutex
+}
+
+// NewOutbox creates a new empty outbox
+func NewOutbox() *Outbox {
+	return &Outbox{notify: make(chan struct{}, 1)}
+}
+
+// Write applies the state change and appends its events in one operation,
+// the events are assigned increasing ids which sinks use to deduplicate them
+func (o *Outbox) Write(change func(), events ...models.Event) {
+	o.WriteIf(func() bool {
+		change()
+		return true
+	}, events...)
+}
+
+// WriteIf applies a conditional state change and appends its events only if the change was applied
+func (o *Outbox) WriteIf(change func() bool, events ...models.Event) bool {
+	if o == nil {
+		return change()
+	}
+	o.lock.Lock()
+	if !change() {
+		o.lock.Unlock()
+		return false
+	}
+	for _, e := range events {
+		o.lastID++
+		e.ID = o.lastID
+		o.events = append(o.events, e)
+	}
+	o.lock.Unlock()
+
+	if len(events) > 0 {
+		select {
+		case o.notify <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Pending returns the events which have not been trimmed yet in the order they were written
+func (o *Outbox) Pending() []models.Event {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]models.Event(nil), o.events...)
+}
+
+// Trim removes the events up to and including the given id once all sinks received them
+func (o *Outbox) Trim(upTo uint64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	i := 0
+	for i < len(o.events) && o.events[i].ID <= upTo {
+		i++
+	}
+	o.events = o.events[i:]
+}
+
+// Notify signals that new events were written
+func (o *Outbox) Notify() <-chan struct{} {
+	return o.notify
+}
@@ -2,21 +2,39 @@ package db
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 
 	"github.com/orders-app/models"
+	"github.com/orders-app/search"
 	"github.com/orders-app/utils"
 )
 
+// ProductDB stores the product variants and the families grouping them,
+// a family is added or renamed whenever one of its variants is stored.
+// Products are indexed for text search over their name and variety.
 type ProductDB struct {
 	products sync.Map
+	families sync.Map
+	index    search.Index
+	// writeLock serialises the writes, so compare-and-swap works on products which are not comparable
+	writeLock sync.Mutex
+	outbox    *Outbox
 }
 
-// NewProductDBService creates a new empty products service
-func NewProductDBService() *ProductDB {
-	p := &ProductDB{}
-	utils.ImportProducts(&p.products)
-	return p
+// NewProductDBService creates a new products service writing its events to the outbox,
+// loaded with the catalogue file
+func NewProductDBService(outbox *Outbox, cataloguePath string) (*ProductDB, error) {
+	p := &ProductDB{outbox: outbox}
+	products, err := utils.ImportProducts(cataloguePath)
+	if err != nil {
+		return nil, fmt.Errorf("importing %s: %w", cataloguePath, err)
+	}
+	for _, product := range products {
+		p.store(product)
+	}
+	return p, nil
 }
 
 // Exists checks whether a product with a given id exists
@@ -38,12 +56,66 @@ func (p *ProductDB) Find(id string) (models.Product, error) {
 	return toProduct(prod), nil
 }
 
-// Upsert inserts or updates a product in the database
-func (p *ProductDB) Upsert(product models.Product) {
-	p.products.Store(product.ID, product)
+// Upsert inserts or updates a product in the database together with the events of the change
+func (p *ProductDB) Upsert(product models.Product, events ...models.Event) {
+	p.outbox.Write(func() {
+		p.writeLock.Lock()
+		defer p.writeLock.Unlock()
+		p.store(product)
+	}, events...)
 }
 
-// GetAll lists all products in the database
+// Insert stores a new product together with the events of the change, it does nothing if the product already exists
+func (p *ProductDB) Insert(product models.Product, events ...models.Event) bool {
+	return p.outbox.WriteIf(func() bool {
+		p.writeLock.Lock()
+		defer p.writeLock.Unlock()
+		if _, loaded := p.products.Load(product.ID); loaded {
+			return false
+		}
+		p.store(product)
+		return true
+	}, events...)
+}
+
+// CompareAndSwap updates a product only if it is still in the old state, together with the events of the change
+func (p *ProductDB) CompareAndSwap(old, updated models.Product, events ...models.Event) bool {
+	return p.outbox.WriteIf(func() bool {
+		p.writeLock.Lock()
+		defer p.writeLock.Unlock()
+		return p.swap(ProductSwap{Old: old, New: updated})
+	}, events...)
+}
+
+// ApplyIf stores the swapped products and removes the listed ones in one storage operation together with the events
+// of the change. Nothing is changed unless every product is still in its old state, a swap from a product without
+// an id creates a product which must not exist yet.
+func (p *ProductDB) ApplyIf(swaps []ProductSwap, removals []models.Product, events ...models.Event) bool {
+	return p.outbox.WriteIf(func() bool {
+		p.writeLock.Lock()
+		defer p.writeLock.Unlock()
+		for _, s := range swaps {
+			if !p.matches(s.New.ID, s.Old) {
+				return false
+			}
+		}
+		for _, product := range removals {
+			if !p.matches(product.ID, product) {
+				return false
+			}
+		}
+		for _, s := range swaps {
+			p.store(s.New)
+		}
+		for _, product := range removals {
+			p.products.Delete(product.ID)
+			p.index.Remove(product.ID)
+		}
+		return true
+	}, events...)
+}
+
+// GetAll lists all products in the database ordered by ID
 func (p *ProductDB) GetAll() []models.Product {
 	var allProducts []models.Product
 
@@ -51,10 +123,91 @@ func (p *ProductDB) GetAll() []models.Product {
 		allProducts = append(allProducts, toProduct(value))
 		return true
 	})
+	sort.Slice(allProducts, func(i, j int) bool { return allProducts[i].ID < allProducts[j].ID })
 
 	return allProducts
 }
 
+// Search lists the products whose name or variety match the text, ordered by ID
+func (p *ProductDB) Search(text string) []models.Product {
+	var found []models.Product
+	for id := range p.index.Search(text) {
+		if product, err := p.Find(id); err == nil {
+			found = append(found, product)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// FindFamily returns a family with its variants if it has any
+func (p *ProductDB) FindFamily(id string) (models.Family, error) {
+	for _, family := range p.GetAllFamilies() {
+		if family.ID == id {
+			return family, nil
+		}
+	}
+	return models.Family{}, fmt.Errorf("no product family found for id %s", id)
+}
+
+// GetAllFamilies lists the families which have variants, each with its variants, ordered by family and variant ID
+func (p *ProductDB) GetAllFamilies() []models.Family {
+	variants := make(map[string][]models.Product)
+	for _, product := range p.GetAll() {
+		variants[product.FamilyID] = append(variants[product.FamilyID], product)
+	}
+	var families []models.Family
+	p.families.Range(func(key, value any) bool {
+		family := value.(models.Family)
+		if len(variants[family.ID]) == 0 {
+			return true
+		}
+		family.Variants = variants[family.ID]
+		sort.Slice(family.Variants, func(i, j int) bool { return family.Variants[i].ID < family.Variants[j].ID })
+		families = append(families, family)
+		return true
+	})
+	sort.Slice(families, func(i, j int) bool { return families[i].ID < families[j].ID })
+	return families
+}
+
+// ProductSwap is a change of a product, applied only if the product is still in the old state
+type ProductSwap struct {
+	Old models.Product
+	New models.Product
+}
+
+// swap applies the changes only if all the products are still in their old state, the caller holds the write lock
+func (p *ProductDB) swap(swaps ...ProductSwap) bool {
+	for _, s := range swaps {
+		if s.Old.ID == "" || !p.matches(s.Old.ID, s.Old) {
+			return false
+		}
+	}
+	for _, s := range swaps {
+		p.store(s.New)
+	}
+	return true
+}
+
+// matches checks that the product with the given id is in the old state, or does not exist for an old product without an id.
+// The caller must hold the write lock.
+func (p *ProductDB) matches(id string, old models.Product) bool {
+	current, ok := p.products.Load(id)
+	if old.ID == "" {
+		return !ok
+	}
+	return ok && toProduct(current).Equal(old)
+}
+
+// store saves a product with its family and indexes it, the tags are copied so callers can not change them
+func (p *ProductDB) store(product models.Product) {
+	product.Tags = slices.Clone(product.Tags)
+	p.products.Store(product.ID, product)
+	p.families.Store(product.FamilyID, models.Family{ID: product.FamilyID, Name: product.Name})
+	p.index.Update(product.ID, product.Name, product.Variety)
+}
+
 func toProduct(p any) models.Product {
 	product, ok := p.(models.Product)
 
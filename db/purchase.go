@@ -0,0 +1,126 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// PurchaseDB stores the suppliers, the purchase orders and the audit of the goods received for them
+type PurchaseDB struct {
+	suppliers sync.Map
+	orders    sync.Map
+	// receipts holds the receipts of each purchase order in the order they were received
+	receipts map[string][]models.StockReceipt
+	// writeLock serialises the writes, so compare-and-swap works on purchase orders which are not comparable
+	writeLock sync.Mutex
+	outbox    *Outbox
+}
+
+// NewPurchaseDBService creates a new empty purchasing service writing its events to the outbox
+func NewPurchaseDBService(outbox *Outbox) *PurchaseDB {
+	return &PurchaseDB{
+		receipts: make(map[string][]models.StockReceipt),
+		outbox:   outbox,
+	}
+}
+
+// UpsertSupplier creates or updates a supplier
+func (d *PurchaseDB) UpsertSupplier(supplier models.Supplier) {
+	d.suppliers.Store(supplier.ID, supplier)
+}
+
+// FindSupplier returns a supplier if exists
+func (d *PurchaseDB) FindSupplier(id string) (models.Supplier, error) {
+	supplier, ok := d.suppliers.Load(id)
+	if !ok {
+		return models.Supplier{}, fmt.Errorf("no supplier found for id %s", id)
+	}
+	return supplier.(models.Supplier), nil
+}
+
+// GetAllSuppliers lists all suppliers ordered by creation time
+func (d *PurchaseDB) GetAllSuppliers() []models.Supplier {
+	var all []models.Supplier
+	d.suppliers.Range(func(key, value any) bool {
+		all = append(all, value.(models.Supplier))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt < all[j].CreatedAt })
+	return all
+}
+
+// Find returns a purchase order if exists
+func (d *PurchaseDB) Find(id string) (models.PurchaseOrder, error) {
+	po, ok := d.orders.Load(id)
+	if !ok {
+		return models.PurchaseOrder{}, fmt.Errorf("no purchase order found for id %s", id)
+	}
+	return po.(models.PurchaseOrder), nil
+}
+
+// Insert stores a new purchase order together with the events of the change
+func (d *PurchaseDB) Insert(po models.PurchaseOrder, events ...models.Event) {
+	d.outbox.Write(func() {
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		d.orders.Store(po.ID, po)
+	}, events...)
+}
+
+// CompareAndSwap updates a purchase order only if it is still in the old state, together with the events of the change
+func (d *PurchaseDB) CompareAndSwap(old, updated models.PurchaseOrder, events ...models.Event) bool {
+	return d.outbox.WriteIf(func() bool {
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		if !d.matches(old) {
+			return false
+		}
+		d.orders.Store(updated.ID, updated)
+		return true
+	}, events...)
+}
+
+// Receive updates a purchase order, restocks its products and records the receipts in one storage operation
+// together with the events of the change. Nothing is changed unless the purchase order and all the products
+// are still in their old state.
+func (d *PurchaseDB) Receive(products *ProductDB, old, updated models.PurchaseOrder, stock []ProductSwap, receipts []models.StockReceipt, events ...models.Event) bool {
+	return d.outbox.WriteIf(func() bool {
+		products.writeLock.Lock()
+		defer products.writeLock.Unlock()
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		if !d.matches(old) || !products.swap(stock...) {
+			return false
+		}
+		d.orders.Store(updated.ID, updated)
+		d.receipts[updated.ID] = append(d.receipts[updated.ID], receipts...)
+		return true
+	}, events...)
+}
+
+// GetAll lists all purchase orders ordered by creation time
+func (d *PurchaseDB) GetAll() []models.PurchaseOrder {
+	var all []models.PurchaseOrder
+	d.orders.Range(func(key, value any) bool {
+		all = append(all, value.(models.PurchaseOrder))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt < all[j].CreatedAt })
+	return all
+}
+
+// GetReceipts lists the receipts of a purchase order in the order they were received
+func (d *PurchaseDB) GetReceipts(id string) []models.StockReceipt {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	return append([]models.StockReceipt{}, d.receipts[id]...)
+}
+
+// matches checks whether the stored purchase order is in the given state, the caller holds the write lock
+func (d *PurchaseDB) matches(po models.PurchaseOrder) bool {
+	current, ok := d.orders.Load(po.ID)
+	return ok && current.(models.PurchaseOrder).Equal(po)
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/orders-app/models"
+)
+
+// expiries is a min heap of reservations by the time they expire at
+type expiries []models.Reservation
+
+func (e expiries) Len() int           { return len(e) }
+func (e expiries) Less(i, j int) bool { return e[i].ExpiresAt < e[j].ExpiresAt }
+func (e expiries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e *expiries) Push(x any)        { *e = append(*e, x.(models.Reservation)) }
+func (e *expiries) Pop() any {
+	old := *e
+	last := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return last
+}
+
+// ReservationDB stores the stock reservations
+type ReservationDB struct {
+	reservations sync.Map
+	// writeLock serialises the writes so a reservation changes state only once,
+	// it also guards expiries which holds every reservation until it expires
+	writeLock sync.Mutex
+	expiries  expiries
+	outbox    *Outbox
+}
+
+// NewReservationDBService creates a new empty reservation service writing its events to the outbox
+func NewReservationDBService(outbox *Outbox) *ReservationDB {
+	return &ReservationDB{outbox: outbox}
+}
+
+// Find returns a reservation if exists
+func (d *ReservationDB) Find(id string) (models.Reservation, error) {
+	reservation, ok := d.reservations.Load(id)
+	if !ok {
+		return models.Reservation{}, fmt.Errorf("no reservation found for id %s", id)
+	}
+	return reservation.(models.Reservation), nil
+}
+
+// CompareAndSwap updates a reservation only if it is still in the old state, together with the events of the change
+func (d *ReservationDB) CompareAndSwap(old, updated models.Reservation, events ...models.Event) bool {
+	return d.outbox.WriteIf(func() bool {
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		if !d.matches(old) {
+			return false
+		}
+		d.reservations.Store(updated.ID, updated)
+		return true
+	}, events...)
+}
+
+// Hold stores a new reservation and the product holding its stock in one storage operation together with
+// the events of the change, nothing is changed unless the product is still in its old state
+func (d *ReservationDB) Hold(products *ProductDB, reservation models.Reservation, stock ProductSwap, events ...models.Event) bool {
+	return d.outbox.WriteIf(func() bool {
+		products.writeLock.Lock()
+		defer products.writeLock.Unlock()
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		if !products.swap(stock) {
+			return false
+		}
+		d.reservations.Store(reservation.ID, reservation)
+		heap.Push(&d.expiries, reservation)
+		return true
+	}, events...)
+}
+
+// Settle updates a reservation and returns its stock to the product in one storage operation together with
+// the events of the change, nothing is changed unless the reservation and the product are still in their old state.
+// Without a stock change only the reservation is updated, e.g. when its product was removed.
+func (d *ReservationDB) Settle(products *ProductDB, old, updated models.Reservation, stock []ProductSwap, events ...models.Event) bool {
+	return d.outbox.WriteIf(func() bool {
+		products.writeLock.Lock()
+		defer products.writeLock.Unlock()
+		d.writeLock.Lock()
+		defer d.writeLock.Unlock()
+		if !d.matches(old) || !products.swap(stock...) {
+			return false
+		}
+		d.reservations.Store(updated.ID, updated)
+		return true
+	}, events...)
+}
+
+// Expired takes the reservations which expired at the given time off the expiry index and returns them as they
+// were held, those confirmed or released since are returned as well
+func (d *ReservationDB) Expired(now time.Time) []models.Reservation {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	var expired []models.Reservation
+	for len(d.expiries) > 0 && d.expiries[0].Expired(now) {
+		expired = append(expired, heap.Pop(&d.expiries).(models.Reservation))
+	}
+	return expired
+}
+
+// GetAll lists all reservations ordered by creation time
+func (d *ReservationDB) GetAll() []models.Reservation {
+	var all []models.Reservation
+	d.reservations.Range(func(key, value any) bool {
+		all = append(all, value.(models.Reservation))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt < all[j].CreatedAt })
+	return all
+}
+
+// matches checks whether the stored reservation is in the given state, the caller holds the write lock
+func (d *ReservationDB) matches(r models.Reservation) bool {
+	current, ok := d.reservations.Load(r.ID)
+	return ok && current.(models.Reservation) == r
+}
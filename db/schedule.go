@@ -0,0 +1,72 @@
+package db
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// scheduled is an order waiting for the time it is processed at
+type scheduled struct {
+	orderID   string
+	processAt time.Time
+}
+
+// schedule is a min heap of orders by the time they are processed at
+type schedule []scheduled
+
+func (s schedule) Len() int { return len(s) }
+func (s schedule) Less(i, j int) bool {
+	if s[i].processAt.Equal(s[j].processAt) {
+		return s[i].orderID < s[j].orderID
+	}
+	return s[i].processAt.Before(s[j].processAt)
+}
+func (s schedule) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s *schedule) Push(x any)   { *s = append(*s, x.(scheduled)) }
+func (s *schedule) Pop() any {
+	old := *s
+	last := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return last
+}
+
+// ScheduleDB holds the scheduled orders in the order they are due
+type ScheduleDB struct {
+	orders schedule
+	lock   sync.Mutex
+}
+
+// NewScheduleDBService creates a new empty schedule
+func NewScheduleDBService() *ScheduleDB {
+	return &ScheduleDB{}
+}
+
+// Push schedules an order to be processed at the given time
+func (d *ScheduleDB) Push(orderID string, processAt time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	heap.Push(&d.orders, scheduled{orderID: orderID, processAt: processAt})
+}
+
+// Next returns the earliest order which is due at the given time, it stays on the schedule until it is removed
+func (d *ScheduleDB) Next(now time.Time) (string, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.orders) == 0 || d.orders[0].processAt.After(now) {
+		return "", false
+	}
+	return d.orders[0].orderID, true
+}
+
+// Remove takes an order off the schedule
+func (d *ScheduleDB) Remove(orderID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i, o := range d.orders {
+		if o.orderID == orderID {
+			heap.Remove(&d.orders, i)
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// SubscriptionDB stores the subscriptions and the runs of their cycles
+type SubscriptionDB struct {
+	subscriptions sync.Map
+	runs          map[string][]models.SubscriptionRun
+	// writeLock serialises the writes so a subscription is only updated from its latest state
+	writeLock sync.Mutex
+}
+
+// NewSubscriptionDBService creates a new empty subscription service
+func NewSubscriptionDBService() *SubscriptionDB {
+	return &SubscriptionDB{runs: make(map[string][]models.SubscriptionRun)}
+}
+
+// Find returns a subscription if exists
+func (d *SubscriptionDB) Find(id string) (models.Subscription, error) {
+	subscription, ok := d.subscriptions.Load(id)
+	if !ok {
+		return models.Subscription{}, fmt.Errorf("no subscription found for id %s", id)
+	}
+	return subscription.(models.Subscription), nil
+}
+
+// Insert stores a new subscription
+func (d *SubscriptionDB) Insert(subscription models.Subscription) {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	d.subscriptions.Store(subscription.ID, subscription)
+}
+
+// CompareAndSwap updates a subscription only if it is still in the old state
+func (d *SubscriptionDB) CompareAndSwap(old, updated models.Subscription) bool {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	if !d.matches(old) {
+		return false
+	}
+	d.subscriptions.Store(updated.ID, updated)
+	return true
+}
+
+// Record updates a subscription and appends the run of one of its cycles in one operation,
+// nothing is changed unless the subscription is still in the old state
+func (d *SubscriptionDB) Record(old, updated models.Subscription, run models.SubscriptionRun) bool {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	if !d.matches(old) {
+		return false
+	}
+	d.subscriptions.Store(updated.ID, updated)
+	d.runs[updated.ID] = append(d.runs[updated.ID], run)
+	return true
+}
+
+// GetAll lists all subscriptions ordered by creation time
+func (d *SubscriptionDB) GetAll() []models.Subscription {
+	var all []models.Subscription
+	d.subscriptions.Range(func(key, value any) bool {
+		all = append(all, value.(models.Subscription))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt == all[j].CreatedAt {
+			return all[i].ID < all[j].ID
+		}
+		return all[i].CreatedAt < all[j].CreatedAt
+	})
+	return all
+}
+
+// GetRuns lists the runs of a subscription in the order they were recorded
+func (d *SubscriptionDB) GetRuns(id string) []models.SubscriptionRun {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+	return append([]models.SubscriptionRun{}, d.runs[id]...)
+}
+
+// matches checks whether the stored subscription is in the given state, the caller holds the write lock
+func (d *SubscriptionDB) matches(s models.Subscription) bool {
+	current, ok := d.subscriptions.Load(s.ID)
+	return ok && current.(models.Subscription) == s
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/orders-app/models"
+)
+
+// due is a pending delivery waiting for its next attempt
+type due struct {
+	deliveryID string
+	at         time.Time
+}
+
+// dueQueue is a min heap of pending deliveries by the time of their next attempt
+type dueQueue []due
+
+func (q dueQueue) Len() int           { return len(q) }
+func (q dueQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q dueQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *dueQueue) Push(x any)        { *q = append(*q, x.(due)) }
+func (q *dueQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+type WebhookDB struct {
+	webhooks   sync.Map
+	deliveries sync.Map
+	// pending holds the next attempt of each pending delivery,
+	// settled the succeeded and dead deliveries from the oldest to the latest
+	pending dueQueue
+	settled *list.List
+	settles map[string]*list.Element
+	lock    sync.Mutex
+}
+
+// NewWebhookDBService creates a new empty webhook db service
+func NewWebhookDBService() *WebhookDB {
+	return &WebhookDB{
+		settled: list.New(),
+		settles: make(map[string]*list.Element),
+	}
+}
+
+// UpsertWebhook creates or updates a webhook
+func (w *WebhookDB) UpsertWebhook(webhook models.Webhook) {
+	w.webhooks.Store(webhook.ID, webhook)
+}
+
+// FindWebhook returns a webhook if exists
+func (w *WebhookDB) FindWebhook(id string) (models.Webhook, error) {
+	webhook, ok := w.webhooks.Load(id)
+	if !ok {
+		return models.Webhook{}, fmt.Errorf("no webhook found for id %s", id)
+	}
+	return toWebhook(webhook), nil
+}
+
+// DeleteWebhook removes a webhook
+func (w *WebhookDB) DeleteWebhook(id string) error {
+	if _, ok := w.webhooks.LoadAndDelete(id); !ok {
+		return fmt.Errorf("no webhook found for id %s", id)
+	}
+	return nil
+}
+
+// GetAllWebhooks lists all webhooks ordered by creation time
+func (w *WebhookDB) GetAllWebhooks() []models.Webhook {
+	var all []models.Webhook
+	w.webhooks.Range(func(key, value any) bool {
+		all = append(all, toWebhook(value))
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt < all[j].CreatedAt })
+	return all
+}
+
+// UpsertDelivery creates or updates a delivery, a pending delivery is queued for its next attempt
+func (w *WebhookDB) UpsertDelivery(delivery models.Delivery) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.deliveries.Store(delivery.ID, delivery)
+	w.track(delivery)
+}
+
+// InsertDelivery stores a delivery unless one with the same id exists, and reports whether it was stored
+func (w *WebhookDB) InsertDelivery(delivery models.Delivery) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if _, loaded := w.deliveries.LoadOrStore(delivery.ID, delivery); loaded {
+		return false
+	}
+	w.track(delivery)
+	return true
+}
+
+// NextDueDelivery takes the earliest pending delivery off the queue if it is due at the given time,
+// otherwise it returns when the earliest one is due
+func (w *WebhookDB) NextDueDelivery(now time.Time) (string, time.Time, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.pending) == 0 {
+		return "", time.Time{}, false
+	}
+	if next := w.pending[0]; next.at.After(now) {
+		return "", next.at, false
+	}
+	return heap.Pop(&w.pending).(due).deliveryID, time.Time{}, true
+}
+
+// PruneDeliveries removes the oldest succeeded and dead deliveries so at most keep of them are left
+func (w *WebhookDB) PruneDeliveries(keep int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for w.settled.Len() > keep {
+		id := w.settled.Remove(w.settled.Front()).(string)
+		delete(w.settles, id)
+		w.deliveries.Delete(id)
+	}
+}
+
+// track queues a pending delivery or records when it settled, the caller must hold the lock
+func (w *WebhookDB) track(delivery models.Delivery) {
+	if e, ok := w.settles[delivery.ID]; ok {
+		w.settled.Remove(e)
+		delete(w.settles, delivery.ID)
+	}
+	if delivery.Status == string(models.DeliveryStatus_Pending) {
+		heap.Push(&w.pending, due{deliveryID: delivery.ID, at: delivery.NextAttemptAt})
+		return
+	}
+	w.settles[delivery.ID] = w.settled.PushBack(delivery.ID)
+}
+
+// FindDelivery returns a delivery if exists
+func (w *WebhookDB) FindDelivery(id string) (models.Delivery, error) {
+	delivery, ok := w.deliveries.Load(id)
+	if !ok {
+		return models.Delivery{}, fmt.Errorf("no delivery found for id %s", id)
+	}
+	return toDelivery(delivery), nil
+}
+
+// FindDeliveries lists the deliveries matching the predicate ordered by creation time
+func (w *WebhookDB) FindDeliveries(matches func(models.Delivery) bool) []models.Delivery {
+	var found []models.Delivery
+	w.deliveries.Range(func(key, value any) bool {
+		if d := toDelivery(value); matches(d) {
+			found = append(found, d)
+		}
+		return true
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].CreatedAt < found[j].CreatedAt })
+	return found
+}
+
+func toWebhook(w any) models.Webhook {
+	webhook, ok := w.(models.Webhook)
+	if !ok {
+		panic(fmt.Errorf("error casting %v to webhook", w))
+	}
+	return webhook
+}
+
+func toDelivery(d any) models.Delivery {
+	delivery, ok := d.(models.Delivery)
+	if !ok {
+		panic(fmt.Errorf("error casting %v to delivery", d))
+	}
+	return delivery
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// subscriberBuffer is how many events a subscriber can lag behind before it is dropped
+const subscriberBuffer = 64
+
+// Filter selects the events a subscriber is interested in, empty fields match everything
+type Filter struct {
+	OrderID   string
+	ProductID string
+}
+
+// Matches checks whether the event passes the filter
+func (f Filter) Matches(e models.Event) bool {
+	if f.OrderID != "" && f.OrderID != e.OrderID {
+		return false
+	}
+	if f.ProductID != "" && f.ProductID != e.ProductID {
+		return false
+	}
+	return true
+}
+
+// Subscription receives the events matching its filter
+type Subscription struct {
+	// Replay holds the buffered events published after the requested event id
+	Replay []models.Event
+	// Events receives the live events, it is closed when the subscriber falls too far behind
+	Events <-chan models.Event
+	events chan models.Event
+	filter Filter
+	broker *Broker
+	once   sync.Once
+}
+
+// Close unsubscribes from the broker
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans events out to subscribers and keeps the latest events for replay.
+// Publishing never blocks: subscribers which can not keep up are dropped and can resume from the replay buffer.
+type Broker struct {
+	replay      []models.Event
+	replaySize  int
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+	lock        sync.Mutex
+}
+
+// NewBroker creates a broker keeping the given number of events for replay
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		replaySize:  replaySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Name identifies the broker as a sink of the relay
+func (b *Broker) Name() string {
+	return "broker"
+}
+
+// Publish delivers the event to all matching subscribers.
+// Events which were already published are dropped, events without an id are assigned the next one.
+func (b *Broker) Publish(event models.Event) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if event.ID == 0 {
+		event.ID = b.lastID + 1
+	}
+	if event.ID <= b.lastID {
+		return nil
+	}
+	b.lastID = event.ID
+	b.replay = append(b.replay, event)
+	if len(b.replay) > b.replaySize {
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for s := range b.subscribers {
+		if !s.filter.Matches(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			b.drop(s)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber for the events matching the filter,
+// replaying the buffered events published after lastEventID
+func (b *Broker) Subscribe(filter Filter, lastEventID uint64) *Subscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	events := make(chan models.Event, subscriberBuffer)
+	s := &Subscription{
+		Events: events,
+		events: events,
+		filter: filter,
+		broker: b,
+	}
+	if lastEventID > 0 {
+		for _, e := range b.replay {
+			if e.ID > lastEventID && filter.Matches(e) {
+				s.Replay = append(s.Replay, e)
+			}
+		}
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Subscribers returns the number of active subscribers
+func (b *Broker) Subscribers() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *Broker) unsubscribe(s *Subscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.drop(s)
+}
+
+// drop removes the subscriber and closes its channel, the lock must be held
+func (b *Broker) drop(s *Subscription) {
+	delete(b.subscribers, s)
+	s.once.Do(func() { close(s.events) })
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/metrics"
+	"github.com/orders-app/models"
+)
+
+// relayInterval is how often the relay retries sinks which failed
+const relayInterval = time.Second
+
+// Sink receives the events relayed from the outbox.
+// Events are delivered at least once and in order, sinks deduplicate them by their id.
+type Sink interface {
+	Name() string
+	Publish(event models.Event) error
+}
+
+// Relay publishes the events of the outbox to the registered sinks
+type Relay struct {
+	outbox *db.Outbox
+	sinks  []Sink
+	// cursors holds the id of the last event each sink received
+	cursors map[string]uint64
+	lock    sync.Mutex
+}
+
+// RelayMetrics is a snapshot of the relay state
+type RelayMetrics struct {
+	Pending int               `json:"pending"`
+	Cursors map[string]uint64 `json:"cursors"`
+}
+
+// NewRelay creates a relay and starts publishing the events of the outbox to the sinks
+func NewRelay(outbox *db.Outbox, sinks ...Sink) *Relay {
+	r := &Relay{
+		outbox:  outbox,
+		sinks:   sinks,
+		cursors: make(map[string]uint64),
+	}
+	metrics.Register("outbox", func() any { return r.metrics() })
+	go r.run()
+	return r
+}
+
+// run relays the events whenever new ones are written, retrying failed sinks periodically
+func (r *Relay) run() {
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.outbox.Notify():
+		case <-ticker.C:
+		}
+		r.relay()
+	}
+}
+
+// relay publishes the pending events to every sink in order, a sink which fails
+// stops receiving events until the failed one is published successfully
+func (r *Relay) relay() {
+	pending := r.outbox.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var trimUpTo uint64
+	for i, sink := range r.sinks {
+		cursor := r.cursors[sink.Name()]
+		for _, e := range pending {
+			if e.ID <= cursor {
+				continue
+			}
+			if err := sink.Publish(e); err != nil {
+				logger.Log.Warn(fmt.Sprintf("Relaying event %d to %s failed: %v", e.ID, sink.Name(), err))
+				break
+			}
+			cursor = e.ID
+		}
+		r.cursors[sink.Name()] = cursor
+		if i == 0 || cursor < trimUpTo {
+			trimUpTo = cursor
+		}
+	}
+	r.outbox.Trim(trimUpTo)
+}
+
+func (r *Relay) metrics() RelayMetrics {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	cursors := make(map[string]uint64, len(r.cursors))
+	for name, cursor := range r.cursors {
+		cursors[name] = cursor
+	}
+	return RelayMetrics{
+		Pending: len(r.outbox.Pending()),
+		Cursors: cursors,
+	}
+}
+
+// LogSink logs every event
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return "log"
+}
+
+func (LogSink) Publish(e models.Event) error {
+	logger.Log.Info(fmt.Sprintf("Event %d %s order=%s product=%s", e.ID, e.Type, e.OrderID, e.ProductID))
+	return nil
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakySink fails to publish while it is down
+type flakySink struct {
+	received []uint64
+	down     bool
+	lock     sync.Mutex
+}
+
+func (s *flakySink) Name() string { return "flaky" }
+
+func (s *flakySink) Publish(e models.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.down {
+		return errors.New("sink is down")
+	}
+	s.received = append(s.received, e.ID)
+	return nil
+}
+
+func (s *flakySink) setDown(down bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.down = down
+}
+
+func Test_Relay(t *testing.T) {
+	logger.InitLogger("test")
+	outbox := db.NewOutbox()
+	sink := &flakySink{down: true}
+	broker := NewBroker(10)
+	sub := broker.Subscribe(Filter{}, 0)
+	r := &Relay{outbox: outbox, sinks: []Sink{broker, sink}, cursors: make(map[string]uint64)}
+
+	stock := 0
+	outbox.Write(func() { stock = 5 }, models.NewProductEvent(models.EventType_StockChanged, models.Product{ID: "one"}))
+	outbox.Write(func() { stock = 4 }, models.NewProductEvent(models.EventType_StockChanged, models.Product{ID: "two"}))
+	assert.Equal(t, 4, stock)
+
+	// the healthy sink receives the events even though the other one fails
+	r.relay()
+	assert.Equal(t, uint64(1), (<-sub.Events).ID)
+	assert.Equal(t, uint64(2), (<-sub.Events).ID)
+	assert.Len(t, outbox.Pending(), 2)
+	assert.Empty(t, sink.received)
+
+	// the failed sink catches up in order and the outbox is trimmed
+	sink.setDown(false)
+	r.relay()
+	assert.Equal(t, []uint64{1, 2}, sink.received)
+	assert.Empty(t, outbox.Pending())
+
+	// a conditional change which is not applied writes no event
+	applied := outbox.WriteIf(func() bool { return false }, models.NewProductEvent(models.EventType_StockChanged, models.Product{ID: "three"}))
+	assert.False(t, applied)
+	assert.Empty(t, outbox.Pending())
+
+	select {
+	case e := <-sub.Events:
+		t.Fatalf("unexpected event %d", e.ID)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,51 @@
+package graphqlapi
+
+import (
+	"errors"
+
+	"github.com/orders-app/repo"
+)
+
+// error codes reported in the extensions of GraphQL errors
+const (
+	codeBadRequest      = "BAD_REQUEST"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeNotFound        = "NOT_FOUND"
+	codeUnavailable     = "UNAVAILABLE"
+	codeRateLimited     = "RATE_LIMITED"
+)
+
+// resolverError is an error which carries a code in its GraphQL extensions
+type resolverError struct {
+	code string
+	err  error
+}
+
+func newError(code string, err error) *resolverError {
+	return &resolverError{code: code, err: err}
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+func notFound(err error) error {
+	return newError(codeNotFound, err)
+}
+
+// repoError converts a repo error to a resolver error, overload is reported as unavailable
+func repoError(err error) error {
+	if errors.Is(err, repo.ErrOverloaded) || errors.Is(err, repo.ErrClosed) {
+		return newError(codeUnavailable, err)
+	}
+	return newError(codeBadRequest, err)
+}
@@ -0,0 +1,118 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/orders-app/repo"
+)
+
+// request is the body of a GraphQL request
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// handler serves GraphQL requests resolved through the repo
+type handler struct {
+	repo repo.Repo
+}
+
+// NewHandler creates an HTTP handler serving GraphQL queries sent as
+// a JSON body or, for queries only, in the query parameters of a GET request
+func NewHandler(r repo.Repo) http.Handler {
+	return &handler{repo: r}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		writeResult(w, http.StatusBadRequest, errorResult(codeBadRequest, err))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if validation := graphql.ValidateDocument(&schema, doc, nil); !validation.IsValid {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	if err := checkLimits(doc, req.OperationName); err != nil {
+		writeResult(w, http.StatusBadRequest, errorResult(codeBadRequest, err))
+		return
+	}
+	if r.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+		writeResult(w, http.StatusMethodNotAllowed, errorResult(codeBadRequest, errors.New("mutations require a POST request")))
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		Root:          h.repo,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       r.Context(),
+	})
+	writeResult(w, http.StatusOK, result)
+}
+
+// readRequest reads the GraphQL request from the body of a POST or the query parameters of a GET
+func readRequest(r *http.Request) (request, error) {
+	var req request
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("invalid variables:%v", err)
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, fmt.Errorf("invalid graphql body:%v", err)
+	}
+	if req.Query == "" {
+		return req, errors.New("query is required")
+	}
+	return req, nil
+}
+
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if ok && op.Operation == ast.OperationTypeMutation &&
+			(operationName == "" || (op.Name != nil && op.Name.Value == operationName)) {
+			return true
+		}
+	}
+	return false
+}
+
+func errorResult(code string, err error) *graphql.Result {
+	return &graphql.Result{Errors: gqlerrors.FormatErrors(newError(code, err))}
+}
+
+// writeResult writes a GraphQL result as JSON
+func writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		fmt.Fprintf(w, "error encoding resp %v:%s", result, err)
+	}
+}
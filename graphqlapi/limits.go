@@ -0,0 +1,130 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/orders-app/ratelimit"
+)
+
+const (
+	// maxDepth is the deepest nesting of fields a query may select
+	maxDepth = 6
+	// maxComplexity is the highest cost a query may have, every field costs one
+	// and the fields selected below a list are counted listCost times
+	maxComplexity = 500
+	// listCost is the number of elements a list is assumed to hold when computing the complexity
+	listCost = 10
+	// ordersRateLimit is the rate limit policy charged for every order created, like POST /orders
+	ordersRateLimit = "orders"
+)
+
+// queryCost computes the depth and complexity of an operation
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// checkLimits rejects operations which nest fields too deep or select too many of them.
+// It expects a validated document, so fragments can not form cycles.
+func checkLimits(doc *ast.Document, operationName string) error {
+	c := queryCost{fragments: make(map[string]*ast.FragmentDefinition)}
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operations = append(operations, def)
+			}
+		}
+	}
+	for _, op := range operations {
+		var root graphql.Type = schema.QueryType()
+		if op.Operation == ast.OperationTypeMutation {
+			root = schema.MutationType()
+		}
+		complexity, depth := c.selectionSet(op.SelectionSet, root)
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, maxDepth)
+		}
+		if complexity > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, maxComplexity)
+		}
+	}
+	return nil
+}
+
+// selectionSet returns the complexity and depth of the selections made on the parent type
+func (c queryCost) selectionSet(ss *ast.SelectionSet, parent graphql.Type) (int, int) {
+	if ss == nil {
+		return 0, 0
+	}
+	complexity, depth := 0, 0
+	for _, selection := range ss.Selections {
+		var cost, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost, d = c.field(selection, parent)
+		case *ast.InlineFragment:
+			cost, d = c.selectionSet(selection.SelectionSet, parent)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				cost, d = c.selectionSet(fragment.SelectionSet, parent)
+			}
+		}
+		complexity += cost
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+func (c queryCost) field(f *ast.Field, parent graphql.Type) (int, int) {
+	// introspection is free so tools can always load the schema
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return 0, 0
+	}
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return 1, 1
+	}
+	def, ok := object.Fields()[f.Name.Value]
+	if !ok {
+		return 1, 1
+	}
+	fieldType, multiplier := unwrap(def.Type)
+	complexity, depth := c.selectionSet(f.SelectionSet, fieldType)
+	return 1 + multiplier*complexity, 1 + depth
+}
+
+// unwrap strips the non-null and list wrappers of a type, returning how often its fields are selected
+func unwrap(t graphql.Type) (graphql.Type, int) {
+	multiplier := 1
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			multiplier *= listCost
+			t = wrapped.OfType
+		default:
+			return t, multiplier
+		}
+	}
+}
+
+// charge takes cost tokens of the rate limit policy from the caller, requests served without rate limits are not charged
+func charge(ctx context.Context, policy string, cost int) error {
+	c, ok := ratelimit.ChargeFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if res := c(policy, cost); !res.Allowed {
+		return newError(codeRateLimited, fmt.Errorf("rate limit exceeded, please try again in %d seconds", int(math.Ceil(res.RetryAfter.Seconds()))))
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// statsTimeout is how long the stats query waits for the statistics, matching GET /stats
+const statsTimeout = 100 * time.Millisecond
+
+var productType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":             &graphql.Field{Type: graphql.String},
+		"familyId":         &graphql.Field{Type: graphql.String},
+		"variety":          &graphql.Field{Type: graphql.String},
+		"category":         &graphql.Field{Type: graphql.String},
+		"tags":             &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"price":            &graphql.Field{Type: graphql.Float},
+		"stock":            &graphql.Field{Type: graphql.Int},
+		"reserved":         &graphql.Field{Type: graphql.Int},
+		"allowBackorder":   &graphql.Field{Type: graphql.Boolean},
+		"reorderThreshold": &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var itemType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Item",
+	Fields: graphql.Fields{
+		"productId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"product": &graphql.Field{
+			Type:        productType,
+			Description: "The ordered product with its current stock",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				item := p.Source.(models.Item)
+				product, err := repoFrom(p).GetProduct(item.ProductID)
+				if err != nil {
+					return nil, notFound(err)
+				}
+				return product, nil
+			},
+		},
+	},
+})
+
+var orderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Order",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"item":          &graphql.Field{Type: graphql.NewNonNull(itemType)},
+		"familyId":      &graphql.Field{Type: graphql.String},
+		"total":         &graphql.Field{Type: graphql.Float},
+		"error":         &graphql.Field{Type: graphql.String},
+		"createdAt":     &graphql.Field{Type: graphql.String},
+		"status":        &graphql.Field{Type: graphql.String},
+		"reservationId": &graphql.Field{Type: graphql.String},
+		"backorderedAt": &graphql.Field{Type: graphql.String},
+		"processAt":     &graphql.Field{Type: graphql.String},
+		"returned":      &graphql.Field{Type: graphql.Int},
+		"refunded":      &graphql.Field{Type: graphql.Float},
+		"owner":         &graphql.Field{Type: graphql.String},
+	},
+})
+
+// totalsFields are the fields of the totals of processed orders
+func totalsFields() graphql.Fields {
+	return graphql.Fields{
+		"completedOrders":   &graphql.Field{Type: graphql.Int},
+		"rejectedOrders":    &graphql.Field{Type: graphql.Int},
+		"reversedOrders":    &graphql.Field{Type: graphql.Int},
+		"backorderedOrders": &graphql.Field{Type: graphql.Int},
+		"cancelledOrders":   &graphql.Field{Type: graphql.Int},
+		"revenue":           &graphql.Field{Type: graphql.Float},
+	}
+}
+
+var totalsType = graphql.NewObject(graphql.ObjectConfig{
+	Name:   "Totals",
+	Fields: totalsFields(),
+})
+
+var rollupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Rollup",
+	Fields: graphql.Fields{
+		"key":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "The family or variant rolled up"},
+		"totals": &graphql.Field{Type: graphql.NewNonNull(totalsType)},
+	},
+})
+
+var statisticsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Statistics",
+	Fields: func() graphql.Fields {
+		fields := totalsFields()
+		fields["byFamily"] = &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(rollupType))}
+		fields["byVariant"] = &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(rollupType))}
+		return fields
+	}(),
+})
+
+// statistics resolves the totals of all orders next to their roll-ups by family and by variant
+type statistics models.Statistics
+
+// rollup is the totals of one family or variant
+type rollup struct {
+	Key    string        `json:"key"`
+	Totals models.Totals `json:"totals"`
+}
+
+// Resolve resolves the roll-ups as lists ordered by their key and every other field from the totals
+func (s statistics) Resolve(p graphql.ResolveParams) (any, error) {
+	switch p.Info.FieldName {
+	case "byFamily":
+		return rollups(s.ByFamily), nil
+	case "byVariant":
+		return rollups(s.ByVariant), nil
+	}
+	p.Source = s.Totals
+	return graphql.DefaultResolveFn(p)
+}
+
+func rollups(totals map[string]models.Totals) []rollup {
+	list := make([]rollup, 0, len(totals))
+	for key, t := range totals {
+		list = append(list, rollup{Key: key, Totals: t})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"order": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: authorized(auth.Role_Customer, func(p graphql.ResolveParams) (any, error) {
+				order, err := repoFrom(p).GetOrder(p.Args["id"].(string))
+				if err != nil {
+					return nil, notFound(err)
+				}
+				return order, nil
+			}),
+		},
+		"orders": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderType))),
+			Args: graphql.FieldConfigArgument{
+				"status":    &graphql.ArgumentConfig{Type: graphql.String},
+				"productId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: authorized(auth.Role_Operator, func(p graphql.ResolveParams) (any, error) {
+				status, _ := p.Args["status"].(string)
+				productID, _ := p.Args["productId"].(string)
+				orders := repoFrom(p).GetOrders(models.OrderFilter{Status: status, ProductID: productID})
+				if orders == nil {
+					orders = []models.Order{}
+				}
+				return orders, nil
+			}),
+		},
+		"product": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: authorized(auth.Role_Customer, func(p graphql.ResolveParams) (any, error) {
+				product, err := repoFrom(p).GetProduct(p.Args["id"].(string))
+				if err != nil {
+					return nil, notFound(err)
+				}
+				return product, nil
+			}),
+		},
+		"products": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))),
+			Resolve: authorized(auth.Role_Customer, func(p graphql.ResolveParams) (any, error) {
+				return repoFrom(p).GetAllProducts(), nil
+			}),
+		},
+		"stats": &graphql.Field{
+			Type: statisticsType,
+			Resolve: authorized(auth.Role_Operator, func(p graphql.ResolveParams) (any, error) {
+				ctx, cancel := context.WithTimeout(p.Context, statsTimeout)
+				defer cancel()
+				stats, err := repoFrom(p).GetOrderStats(ctx)
+				if err != nil {
+					return nil, repoError(err)
+				}
+				return statistics(stats), nil
+			}),
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"createOrder": &graphql.Field{
+			Type:        orderType,
+			Description: "Accepts an order, it is processed asynchronously",
+			Args: graphql.FieldConfigArgument{
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"amount":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: authorized(auth.Role_Customer, func(p graphql.ResolveParams) (any, error) {
+				if err := charge(p.Context, ordersRateLimit, 1); err != nil {
+					return nil, err
+				}
+				order, err := repoFrom(p).CreateOrder(p.Context, models.Item{
+					ProductID: p.Args["productId"].(string),
+					Amount:    p.Args["amount"].(int),
+				})
+				if err != nil {
+					return nil, repoError(err)
+				}
+				return *order, nil
+			}),
+		},
+		"reverseOrder": &graphql.Field{
+			Type:        orderType,
+			Description: "Requests the reversal of a completed order",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: authorized(auth.Role_Operator, func(p graphql.ResolveParams) (any, error) {
+				order, err := repoFrom(p).RequestReversal(p.Context, p.Args["id"].(string))
+				if err != nil {
+					return nil, repoError(err)
+				}
+				return *order, nil
+			}),
+		},
+	},
+})
+
+// schema is the GraphQL schema of the app, resolvers read the repo from the root value
+var schema = mustSchema(graphql.SchemaConfig{
+	Query:    queryType,
+	Mutation: mutationType,
+})
+
+func mustSchema(cfg graphql.SchemaConfig) graphql.Schema {
+	s, err := graphql.NewSchema(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func repoFrom(p graphql.ResolveParams) repo.Repo {
+	return p.Info.RootValue.(repo.Repo)
+}
+
+// authorized only calls the resolver for principals with at least the given role
+func authorized(role auth.Role, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if _, err := auth.Authorize(p.Context, role); err != nil {
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				return nil, newError(codeUnauthenticated, err)
+			}
+			return nil, newError(codeForbidden, err)
+		}
+		return resolve(p)
+	}
+}
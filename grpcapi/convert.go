@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"github.com/orders-app/grpcapi/ordersv1"
+	"github.com/orders-app/models"
+)
+
+func toOrderPB(o models.Order) *ordersv1.Order {
+	return &ordersv1.Order{
+		Id: o.ID,
+		Item: &ordersv1.Item{
+			ProductId: o.Item.ProductID,
+			Amount:    int64(o.Item.Amount),
+		},
+		Total:         o.Total,
+		Error:         o.Error,
+		CreatedAt:     o.CreatedAt,
+		Status:        o.Status,
+		FamilyId:      o.FamilyID,
+		ReservationId: o.ReservationID,
+		BackorderedAt: o.BackorderedAt,
+		ProcessAt:     o.ProcessAt,
+		Returned:      int64(o.Returned),
+		Refunded:      o.Refunded,
+		Owner:         o.Owner,
+	}
+}
+
+func toProductPB(p models.Product) *ordersv1.Product {
+	return &ordersv1.Product{
+		Id:               p.ID,
+		Name:             p.Name,
+		Variety:          p.Variety,
+		FamilyId:         p.FamilyID,
+		Category:         p.Category,
+		Tags:             p.Tags,
+		Price:            p.Price,
+		Stock:            int64(p.Stock),
+		ReorderThreshold: int64(p.ReorderThreshold),
+		AllowBackorder:   p.AllowBackorder,
+		Reserved:         int64(p.Reserved),
+	}
+}
+
+func toEventPB(e models.Event) *ordersv1.Event {
+	event := &ordersv1.Event{
+		Id:        e.ID,
+		Type:      string(e.Type),
+		OrderId:   e.OrderID,
+		ProductId: e.ProductID,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.Order != nil {
+		event.Order = toOrderPB(*e.Order)
+	}
+	if e.Product != nil {
+		event.Product = toProductPB(*e.Product)
+	}
+	return event
+}
+
+func toStatisticsPB(s models.Statistics) *ordersv1.Statistics {
+	return &ordersv1.Statistics{
+		CompletedOrders:   int64(s.CompletedOrders),
+		RejectedOrders:    int64(s.RejectedOrders),
+		ReversedOrders:    int64(s.ReversedOrders),
+		BackorderedOrders: int64(s.BackorderedOrders),
+		CancelledOrders:   int64(s.CancelledOrders),
+		Revenue:           s.Revenue,
+		ByFamily:          toTotalsPB(s.ByFamily),
+		ByVariant:         toTotalsPB(s.ByVariant),
+	}
+}
+
+func toTotalsPB(rollup map[string]models.Totals) map[string]*ordersv1.Totals {
+	if len(rollup) == 0 {
+		return nil
+	}
+	totals := make(map[string]*ordersv1.Totals, len(rollup))
+	for key, t := range rollup {
+		totals[key] = &ordersv1.Totals{
+			CompletedOrders:   int64(t.CompletedOrders),
+			RejectedOrders:    int64(t.RejectedOrders),
+			ReversedOrders:    int64(t.ReversedOrders),
+			BackorderedOrders: int64(t.BackorderedOrders),
+			CancelledOrders:   int64(t.CancelledOrders),
+			Revenue:           t.Revenue,
+		}
+	}
+	return totals
+}
@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ToOrderPB(t *testing.T) {
+	order := models.Order{
+		ID:            "order",
+		Item:          models.Item{ProductID: "MWBLU", Amount: 3},
+		FamilyID:      "blueberry",
+		Total:         4.5,
+		CreatedAt:     "2031-01-01 09:00:00.000",
+		Status:        string(models.OrderStatus_Completed),
+		ReservationID: "reservation",
+		BackorderedAt: "2031-01-01 09:30:00.000",
+		ProcessAt:     "2031-01-01 08:00:00.000",
+		Returned:      1,
+		Refunded:      1.5,
+		Owner:         "customer",
+	}
+	pb := toOrderPB(order)
+	assert.Equal(t, "order", pb.GetId())
+	assert.Equal(t, "MWBLU", pb.GetItem().GetProductId())
+	assert.Equal(t, int64(3), pb.GetItem().GetAmount())
+	assert.Equal(t, "blueberry", pb.GetFamilyId())
+	assert.Equal(t, 4.5, pb.GetTotal())
+	assert.Equal(t, "Completed", pb.GetStatus())
+	assert.Equal(t, "reservation", pb.GetReservationId())
+	assert.Equal(t, "2031-01-01 09:30:00.000", pb.GetBackorderedAt())
+	assert.Equal(t, "2031-01-01 08:00:00.000", pb.GetProcessAt())
+	assert.Equal(t, int64(1), pb.GetReturned())
+	assert.Equal(t, 1.5, pb.GetRefunded())
+	assert.Equal(t, "customer", pb.GetOwner())
+}
+
+func Test_ToStatisticsPB(t *testing.T) {
+	totals := models.Totals{CompletedOrders: 2, RejectedOrders: 1, CancelledOrders: 1, Revenue: 9}
+	pb := toStatisticsPB(models.Statistics{
+		Totals:    totals,
+		ByFamily:  map[string]models.Totals{"blueberry": totals},
+		ByVariant: map[string]models.Totals{"MWBLU": totals},
+	})
+	assert.Equal(t, int64(2), pb.GetCompletedOrders())
+	assert.Equal(t, int64(1), pb.GetCancelledOrders())
+	assert.Equal(t, 9.0, pb.GetRevenue())
+	assert.Equal(t, int64(2), pb.GetByFamily()["blueberry"].GetCompletedOrders())
+	assert.Equal(t, int64(1), pb.GetByVariant()["MWBLU"].GetRejectedOrders())
+
+	assert.Nil(t, toStatisticsPB(models.Statistics{}).GetByFamily())
+}
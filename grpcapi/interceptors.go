@@ -0,0 +1,137 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/orders-app/auth"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles holds the minimum role allowed to call each method, mirroring the HTTP routes
+var methodRoles = map[string]auth.Role{
+	"/orders.v1.OrdersService/CreateOrder":     auth.Role_Customer,
+	"/orders.v1.OrdersService/GetOrder":        auth.Role_Customer,
+	"/orders.v1.OrdersService/ListProducts":    auth.Role_Customer,
+	"/orders.v1.OrdersService/ListOrders":      auth.Role_Operator,
+	"/orders.v1.OrdersService/RequestReversal": auth.Role_Operator,
+	"/orders.v1.OrdersService/GetStats":        auth.Role_Operator,
+	"/orders.v1.OrdersService/WatchOrders":     auth.Role_Operator,
+}
+
+// wrappedStream replaces the context of a server stream
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+func tracingUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, span := startSpan(ctx, info.FullMethod)
+	defer span.End()
+	return handler(ctx, req)
+}
+
+func tracingStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+func authUnary(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func loggingUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logRequest(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func loggingStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logRequest(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// authorize authenticates the credentials of the call metadata and checks the role required by the method
+func authorize(ctx context.Context, authenticator *auth.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := authenticator.AuthenticateCredentials(auth.Credentials{
+		APIKey:        firstValue(md, "x-api-key"),
+		Authorization: firstValue(md, "authorization"),
+	})
+	if err != nil && !errors.Is(err, auth.ErrNoCredentials) {
+		return ctx, status.Errorf(codes.Unauthenticated, "authentication failed:%v", err)
+	}
+	if err == nil {
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+	role, ok := methodRoles[method]
+	if !ok {
+		// methods have to be listed explicitly, so a new method is never public by accident
+		return ctx, status.Errorf(codes.PermissionDenied, "no role configured for %s", method)
+	}
+	_, err = auth.Authorize(ctx, role)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return ctx, nil
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, oteltrace.Span) {
+	clientIP := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		clientIP = p.Addr.String()
+	}
+	return tracing.StartSpan(ctx, "orders-app", method,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", method),
+		attribute.String("rpc.client_ip", clientIP),
+	)
+}
+
+func logRequest(ctx context.Context, method string, start time.Time, err error) {
+	principal, _ := auth.FromContext(ctx)
+	logger.LogRequest("grpc", method, status.Code(err).String(), time.Since(start), principal.Subject)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package grpcapi
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/orders-app/app"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/events"
+	"github.com/orders-app/grpcapi/ordersv1"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxWait is the longest a client can wait for an order to be processed
+const maxWait = 30 * time.Second
+
+// server implements the OrdersService on top of the repo
+type server struct {
+	ordersv1.UnimplementedOrdersServiceServer
+	repo   repo.Repo
+	events *events.Broker
+}
+
+// NewServer creates a gRPC server serving the OrdersService with the
+// same authentication, logging and tracing as the HTTP API
+func NewServer(a *app.App, authenticator *auth.Authenticator) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(tracingUnary, authUnary(authenticator), loggingUnary),
+		grpc.ChainStreamInterceptor(tracingStream, authStream(authenticator), loggingStream),
+	)
+	ordersv1.RegisterOrdersServiceServer(s, &server{repo: a.Repo, events: a.Events})
+	return s
+}
+
+// CreateOrder accepts a new order, it is processed asynchronously
+func (s *server) CreateOrder(ctx context.Context, req *ordersv1.CreateOrderRequest) (*ordersv1.Order, error) {
+	item := models.Item{
+		ProductID: req.GetItem().GetProductId(),
+		Amount:    int(req.GetItem().GetAmount()),
+	}
+	order, err := s.repo.CreateOrder(ctx, item)
+	if err != nil {
+		return nil, toStatus(err, codes.InvalidArgument)
+	}
+	return toOrderPB(*order), nil
+}
+
+// GetOrder returns an order, waiting up to wait_ms for it to be processed
+func (s *server) GetOrder(ctx context.Context, req *ordersv1.GetOrderRequest) (*ordersv1.Order, error) {
+	wait := min(time.Duration(req.GetWaitMs())*time.Millisecond, maxWait)
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	order, err := s.repo.WaitForOrder(waitCtx, req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return toOrderPB(order), nil
+}
+
+// ListOrders returns the orders matching the request
+func (s *server) ListOrders(ctx context.Context, req *ordersv1.ListOrdersRequest) (*ordersv1.ListOrdersResponse, error) {
+	orders := s.repo.GetOrders(models.OrderFilter{
+		Status:    req.GetStatus(),
+		ProductID: req.GetProductId(),
+	})
+	resp := &ordersv1.ListOrdersResponse{}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, toOrderPB(o))
+	}
+	return resp, nil
+}
+
+// RequestReversal requests the reversal of a completed order
+func (s *server) RequestReversal(ctx context.Context, req *ordersv1.RequestReversalRequest) (*ordersv1.Order, error) {
+	if _, err := s.repo.GetOrder(req.GetId()); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	order, err := s.repo.RequestReversal(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err, codes.FailedPrecondition)
+	}
+	return toOrderPB(*order), nil
+}
+
+// GetStats returns the order statistics, honouring the deadline of the client
+func (s *server) GetStats(ctx context.Context, req *ordersv1.GetStatsRequest) (*ordersv1.Statistics, error) {
+	stats, err := s.repo.GetOrderStats(ctx)
+	if err != nil {
+		return nil, toStatus(err, codes.Internal)
+	}
+	return toStatisticsPB(stats), nil
+}
+
+// ListProducts returns all products
+func (s *server) ListProducts(ctx context.Context, req *ordersv1.ListProductsRequest) (*ordersv1.ListProductsResponse, error) {
+	resp := &ordersv1.ListProductsResponse{}
+	for _, p := range s.repo.GetAllProducts() {
+		resp.Products = append(resp.Products, toProductPB(p))
+	}
+	return resp, nil
+}
+
+// WatchOrders streams the events of the selected orders and products until the client goes away
+func (s *server) WatchOrders(req *ordersv1.WatchOrdersRequest, stream ordersv1.OrdersService_WatchOrdersServer) error {
+	orderIDs := toSet(req.GetOrderIds())
+	productIDs := toSet(req.GetProductIds())
+	matches := func(e models.Event) bool {
+		if len(orderIDs) == 0 && len(productIDs) == 0 {
+			return true
+		}
+		_, order := orderIDs[e.OrderID]
+		_, product := productIDs[e.ProductID]
+		return order || product
+	}
+
+	sub := s.events.Subscribe(events.Filter{}, req.GetLastEventId())
+	defer sub.Close()
+	for _, e := range sub.Replay {
+		if !matches(e) {
+			continue
+		}
+		if err := stream.Send(toEventPB(e)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "watcher fell behind, resume with last_event_id")
+			}
+			if !matches(e) {
+				continue
+			}
+			if err := stream.Send(toEventPB(e)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// toStatus converts a repo error to a gRPC status, using the fallback code for validation errors
+func toStatus(err error, fallback codes.Code) error {
+	switch {
+	case errors.Is(err, repo.ErrOverloaded), errors.Is(err, repo.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(fallback, err.Error())
+	}
+}
+
+func toSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
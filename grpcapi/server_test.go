@@ -0,0 +1,149 @@
+package grpcapi_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orders-app/app"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/grpcapi"
+	"github.com/orders-app/grpcapi/ordersv1"
+	"github.com/orders-app/logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	customerKey = "customer-key"
+	operatorKey = "operator-key"
+)
+
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	logger.InitLogger("test")
+	code := m.Run()
+	os.Exit(code)
+}
+
+func Test_OrdersService(t *testing.T) {
+	client := initClient(t)
+
+	t.Run("missing credentials", func(t *testing.T) {
+		_, err := client.ListProducts(context.Background(), &ordersv1.ListProductsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("insufficient role", func(t *testing.T) {
+		_, err := client.ListOrders(withKey(customerKey), &ordersv1.ListOrdersRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("list products", func(t *testing.T) {
+		resp, err := client.ListProducts(withKey(customerKey), &ordersv1.ListProductsRequest{})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, resp.GetProducts())
+	})
+
+	t.Run("invalid order", func(t *testing.T) {
+		_, err := client.CreateOrder(withKey(customerKey), &ordersv1.CreateOrderRequest{
+			Item: &ordersv1.Item{ProductId: "MWBLU", Amount: 0},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("create and wait for order", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(withKey(operatorKey), 5*time.Second)
+		defer cancel()
+		stream, err := client.WatchOrders(watchCtx, &ordersv1.WatchOrdersRequest{ProductIds: []string{"MWBLU"}})
+		assert.Nil(t, err)
+
+		created, err := client.CreateOrder(withKey(customerKey), &ordersv1.CreateOrderRequest{
+			Item: &ordersv1.Item{ProductId: "MWBLU", Amount: 1},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "New", created.GetStatus())
+
+		order, err := client.GetOrder(withKey(customerKey), &ordersv1.GetOrderRequest{Id: created.GetId(), WaitMs: 2000})
+		assert.Nil(t, err)
+		assert.Equal(t, "Completed", order.GetStatus())
+		assert.NotEmpty(t, order.GetFamilyId())
+		assert.Equal(t, "customer", order.GetOwner())
+
+		event, err := stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, created.GetId(), event.GetOrderId())
+		assert.Equal(t, "order.created", event.GetType())
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		// the statistics are processed with a random delay, so they only show the order eventually
+		var stats *ordersv1.Statistics
+		assert.Eventually(t, func() bool {
+			var err error
+			stats, err = client.GetStats(withKey(operatorKey), &ordersv1.GetStatsRequest{})
+			return err == nil && stats.GetCompletedOrders() == 1
+		}, 10*time.Second, 10*time.Millisecond)
+		assert.Equal(t, int64(1), stats.GetByVariant()["MWBLU"].GetCompletedOrders())
+		assert.Len(t, stats.GetByFamily(), 1)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		_, err := client.GetOrder(withKey(customerKey), &ordersv1.GetOrderRequest{Id: "blablabla"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func initClient(t *testing.T) ordersv1.OrdersServiceClient {
+	a, err := app.New(config.Config{
+		Queue:     config.Queue{Capacity: 10, EnqueueTimeout: time.Second},
+		Events:    config.Events{ReplaySize: 100, Heartbeat: time.Second},
+		Catalogue: config.Catalogue{Path: "./input/products.csv"},
+	})
+	assert.Nil(t, err)
+	t.Cleanup(a.Close)
+	authenticator, err := auth.NewAuthenticator(config.Auth{
+		APIKeys: []config.APIKey{
+			{Name: "customer", Role: string(auth.Role_Customer), Hash: hashKey(customerKey)},
+			{Name: "operator", Role: string(auth.Role_Operator), Hash: hashKey(operatorKey)},
+		},
+		JWTSecret: "secret",
+	})
+	assert.Nil(t, err)
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpcapi.NewServer(a, authenticator)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return ordersv1.NewOrdersServiceClient(conn)
+}
+
+func withKey(apiKey string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", apiKey)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// AlertIndex displays the open stock alerts, with ?status=all also the resolved ones
+func (h *handler) AlertIndex(w http.ResponseWriter, r *http.Request) {
+	switch status := r.URL.Query().Get("status"); status {
+	case "", "open":
+		writeResponse(w, http.StatusOK, h.alerts.Alerts(false), nil)
+	case "all":
+		writeResponse(w, http.StatusOK, h.alerts.Alerts(true), nil)
+	default:
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid status %s, want open or all", status))
+	}
+}
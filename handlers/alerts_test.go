@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AlertIndex(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", "/v1/alerts", adminKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeAlerts(t, rec))
+
+	// MWBLU has 20 in stock and a reorder threshold of 5
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":16}`))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/alerts", adminKey))
+		alerts := decodeAlerts(t, rec)
+		return len(alerts) == 1 && alerts[0].Level == models.AlertLevel_LowStock && alerts[0].Stock == 4
+	}, 2*time.Second, 10*time.Millisecond)
+
+	t.Run("customers are forbidden", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/alerts", customerKey))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/alerts?status=blablabla", adminKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func decodeAlerts(t *testing.T, rec *httptest.ResponseRecorder) []models.Alert {
+	var resp struct {
+		Data []models.Alert `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
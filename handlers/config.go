@@ -4,30 +4,51 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/metrics"
+	"github.com/orders-app/ratelimit"
 )
 
 // ConfigureHandler configures the routes of this handler and binds handler functions to them
-func ConfigureHandler(handler Handler) *mux.Router {
+func ConfigureHandler(handler Handler, authenticator *auth.Authenticator, rateLimits map[string]config.RateLimit) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
 	router.Use(OpenTelemetryMiddleware("orders-app"))
+	router.Use(AuthMiddleware(authenticator))
+	router.Use(LoggingMiddleware)
 
 	router.Methods("GET").Path("/").
 		Handler(http.HandlerFunc(handler.Index))
-	router.Methods("GET").Path("/products").
-		Handler(http.HandlerFunc(handler.ProductIndex))
-	router.Methods("GET").Path("/orders/{orderId}").
-		Handler(http.HandlerFunc(handler.OrderShow))
-	router.Methods("POST").Path("/orders").
-		Handler(http.HandlerFunc(handler.OrderInsert))
-	router.Methods("POST").Path("/close").
-		Handler(http.HandlerFunc(handler.Close))
-	router.Methods("POST").Path("/open").
-		Handler(http.HandlerFunc(handler.Open))
-	router.Methods("GET").
-		Path("/stats").Handler(http.HandlerFunc(handler.Stats))
-	router.Methods("DELETE").Path("/orders/{orderId}").
-		Handler(http.HandlerFunc(handler.OrderReverse))
+
+	limiters := newLimiters(rateLimits)
+	for _, version := range apiVersions(handler) {
+		mountRoutes(router.PathPrefix(version.prefix).Subrouter(), version.routes, authenticator, limiters)
+	}
+
+	// the unversioned routes are kept as deprecated aliases of v1
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(DeprecationMiddleware(legacySunset, "/v1"))
+	mountRoutes(legacy, v1Routes(handler), authenticator, limiters)
 
 	return router
 }
+
+// newLimiters creates one limiter per rate limit policy and exposes its state in the metrics
+func newLimiters(rateLimits map[string]config.RateLimit) map[string]*ratelimit.Limiter {
+	limiters := make(map[string]*ratelimit.Limiter)
+	for name, rl := range rateLimits {
+		limiter := ratelimit.New(rl.Rate, rl.Burst)
+		limiters[name] = limiter
+		metrics.Register("ratelimit."+name, func() any { return limiter.Stats() })
+	}
+	return limiters
+}
+
+// limiterFor returns the limiter of the policy, or the default one for unknown policies
+func limiterFor(limiters map[string]*ratelimit.Limiter, policy string) *ratelimit.Limiter {
+	if limiter, ok := limiters[policy]; ok {
+		return limiter
+	}
+	return limiters[config.DefaultRateLimit]
+}
@@ -0,0 +1,187 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orders-app/app"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/handlers"
+	"github.com/orders-app/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	customerKey      = "customer-key"
+	otherCustomerKey = "other-customer-key"
+	adminKey         = "admin-key"
+)
+
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	logger.InitLogger("test")
+	code := m.Run()
+	os.Exit(code)
+}
+
+func Test_ConfigureHandler(t *testing.T) {
+	router, _ := initRouter(t)
+
+	t.Run("versioned route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Deprecation"))
+	})
+
+	t.Run("deprecated unversioned route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/products", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.NotEmpty(t, rec.Header().Get("Sunset"))
+		assert.Equal(t, `</v1/products>; rel="successor-version"`, rec.Header().Get("Link"))
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/blablabla", customerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func Test_Authorization(t *testing.T) {
+	router, authenticator := initRouter(t)
+
+	t.Run("public route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/products", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("invalid api key", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products", "blablabla"))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("insufficient role", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", "/v1/open", customerKey))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("elevated role", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", "/v1/open", adminKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "already open")
+	})
+
+	t.Run("bearer token", func(t *testing.T) {
+		token, err := authenticator.IssueToken(auth.Principal{Subject: "ops", Role: auth.Role_Admin}, time.Minute)
+		assert.Nil(t, err)
+		req := httptest.NewRequest("POST", "/v1/open", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("expired bearer token", func(t *testing.T) {
+		token, err := authenticator.IssueToken(auth.Principal{Subject: "ops", Role: auth.Role_Admin}, -time.Minute)
+		assert.Nil(t, err)
+		req := httptest.NewRequest("POST", "/v1/open", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func Test_RateLimit(t *testing.T) {
+	router, _ := initRouterWithLimits(t, map[string]config.RateLimit{
+		config.DefaultRateLimit: {Rate: 100, Burst: 100},
+		"orders":                {Rate: 0.5, Burst: 1},
+	})
+	body := `{"productId":"MWBLU","amount":1}`
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, body))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, body))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	// other clients and other policies are not affected
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", adminKey, body))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", "/v1/products", customerKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func initRouter(t *testing.T) (http.Handler, *auth.Authenticator) {
+	return initRouterWithLimits(t, map[string]config.RateLimit{
+		config.DefaultRateLimit: {Rate: 100, Burst: 100},
+	})
+}
+
+func initRouterWithLimits(t *testing.T, limits map[string]config.RateLimit) (http.Handler, *auth.Authenticator) {
+	cfg := config.Config{
+		Queue:     config.Queue{Capacity: 10, EnqueueTimeout: time.Second, MaxBatchSize: 5},
+		Events:    config.Events{ReplaySize: 100, Heartbeat: time.Second},
+		Catalogue: config.Catalogue{Path: "./input/products.csv"},
+	}
+	a, err := app.New(cfg)
+	assert.Nil(t, err)
+	t.Cleanup(a.Close)
+	h := handlers.New(a, cfg.Events)
+	authenticator, err := auth.NewAuthenticator(config.Auth{
+		APIKeys: []config.APIKey{
+			{Name: "customer", Role: string(auth.Role_Customer), Hash: hashKey(customerKey)},
+			{Name: "other-customer", Role: string(auth.Role_Customer), Hash: hashKey(otherCustomerKey)},
+			{Name: "admin", Role: string(auth.Role_Admin), Hash: hashKey(adminKey)},
+		},
+		JWTSecret: "secret",
+	})
+	assert.Nil(t, err)
+	return handlers.ConfigureHandler(h, authenticator, limits), authenticator
+}
+
+func newRequest(method, target, apiKey string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("X-API-Key", apiKey)
+	return req
+}
+
+func newBodyRequest(method, target, apiKey, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-API-Key", apiKey)
+	return req
+}
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
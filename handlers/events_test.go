@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EventStream(t *testing.T) {
+	router, _ := initRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/events?productId=MWLEM", nil)
+	assert.Nil(t, err)
+	req.Header.Set("X-API-Key", adminKey)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// an order of another product is filtered out
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWLEM","amount":1}`))
+	order := decodeOrder(t, rec)
+
+	var received []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(received) < 3 {
+		line := scanner.Text()
+		if eventType, ok := strings.CutPrefix(line, "event: "); ok {
+			received = append(received, eventType)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			assert.Contains(t, data, "MWLEM")
+		}
+	}
+	assert.Equal(t, []string{"order.created", "stock.changed", "order.completed"}, received)
+
+	t.Run("resume from last event id", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/events?orderId="+order.ID, nil)
+		assert.Nil(t, err)
+		req.Header.Set("X-API-Key", adminKey)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if eventType, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				assert.Equal(t, "order.created", eventType)
+				break
+			}
+		}
+	})
+}
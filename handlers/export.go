@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/orders-app/models"
+)
+
+// exportFlushRows is how many rows are written between two flushes of an export
+const exportFlushRows = 100
+
+// exportFormat describes how an export is encoded
+type exportFormat struct {
+	contentType string
+	extension   string
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {contentType: "text/csv; charset=UTF-8", extension: "csv"},
+	"jsonl": {contentType: "application/x-ndjson", extension: "jsonl"},
+	"json":  {contentType: "application/json; charset=UTF-8", extension: "json"},
+}
+
+// orderColumns is the CSV layout of exported orders
+var orderColumns = []string{"ID", "ProductID", "Amount", "Total", "Status", "Error", "CreatedAt"}
+
+// productColumns is the CSV layout of exported products, the columns of input/products.csv followed by the family
+// so exports can be re-imported without losing the families which were set explicitly
+var productColumns = []string{"ID", "ProductName", "Stock", "Variety", "Price", "Category", "Tags", "ReorderThreshold", "AllowBackorder", "FamilyID"}
+
+// OrderExport streams the orders matching the same filters as OrderIndex as csv, jsonl or json,
+// each order is written as it is visited so the export is never held in memory
+func (h *handler) OrderExport(w http.ResponseWriter, r *http.Request) {
+	filter := parseOrderFilter(r)
+	writeExport(w, r, "orders", func(visit func(models.Order) bool) {
+		h.repo.RangeOrders(filter, visit)
+	}, orderColumns, orderRow)
+}
+
+// ProductExport streams the products matching the same search query parameters as ProductIndex as csv, jsonl or json,
+// in the same order but without paging
+func (h *handler) ProductExport(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProductQuery(r)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	query.Page, query.PageSize = 1, math.MaxInt
+	page, err := h.repo.SearchProducts(query)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	writeExport(w, r, "products", func(visit func(models.Product) bool) {
+		for _, p := range page.Products {
+			if !visit(p) {
+				return
+			}
+		}
+	}, productColumns, productRow)
+}
+
+// parseOrderFilter reads the order filter from the query parameters
+func parseOrderFilter(r *http.Request) models.OrderFilter {
+	query := r.URL.Query()
+	return models.OrderFilter{
+		Status:    query.Get("status"),
+		ProductID: query.Get("productId"),
+	}
+}
+
+// exportItems calls visit for every item to export until it returns false
+type exportItems[T any] func(visit func(T) bool)
+
+// writeExport streams the items in the format selected by the format query parameter, csv by default.
+// Rows are encoded straight to the response as they are visited and flushed regularly, so the export is sent in chunks.
+func writeExport[T any](w http.ResponseWriter, r *http.Request, name string, each exportItems[T], columns []string, row func(T) []string) {
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := exportFormats[formatName]
+	if !ok {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid export format %s, want csv, jsonl or json", formatName))
+		return
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format.extension))
+
+	flush := func() {}
+	if flusher, ok := w.(http.Flusher); ok {
+		flush = flusher.Flush
+	}
+	var err error
+	switch formatName {
+	case "csv":
+		err = writeCSV(w, flush, each, columns, row)
+	case "jsonl":
+		err = writeJSONLines(w, flush, each)
+	case "json":
+		err = writeJSONArray(w, flush, each)
+	}
+	if err != nil {
+		// the status is already sent, all that is left is to stop writing
+		return
+	}
+	flush()
+}
+
+func writeCSV[T any](w http.ResponseWriter, flush func(), each exportItems[T], columns []string, row func(T) []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	var err error
+	written := 0
+	each(func(item T) bool {
+		if err = cw.Write(row(item)); err != nil {
+			return false
+		}
+		if written++; written%exportFlushRows == 0 {
+			cw.Flush()
+			flush()
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONLines[T any](w http.ResponseWriter, flush func(), each exportItems[T]) error {
+	enc := json.NewEncoder(w)
+	var err error
+	written := 0
+	each(func(item T) bool {
+		if err = enc.Encode(item); err != nil {
+			return false
+		}
+		if written++; written%exportFlushRows == 0 {
+			flush()
+		}
+		return true
+	})
+	return err
+}
+
+func writeJSONArray[T any](w http.ResponseWriter, flush func(), each exportItems[T]) error {
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	var err error
+	written := 0
+	each(func(item T) bool {
+		if written > 0 {
+			if _, err = w.Write([]byte(",")); err != nil {
+				return false
+			}
+		}
+		var b []byte
+		if b, err = json.Marshal(item); err != nil {
+			return false
+		}
+		if _, err = w.Write(b); err != nil {
+			return false
+		}
+		if written++; written%exportFlushRows == 0 {
+			flush()
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
+
+func orderRow(o models.Order) []string {
+	return []string{
+		o.ID,
+		o.Item.ProductID,
+		strconv.Itoa(o.Item.Amount),
+		strconv.FormatFloat(o.Total, 'f', 2, 64),
+		o.Status,
+		o.Error,
+		o.CreatedAt,
+	}
+}
+
+func productRow(p models.Product) []string {
+	return []string{
+		p.ID,
+		p.Name,
+		strconv.Itoa(p.Stock),
+		p.Variety,
+		strconv.FormatFloat(p.Price, 'f', -1, 64),
+		p.Category,
+		strings.Join(p.Tags, "|"),
+		strconv.Itoa(p.ReorderThreshold),
+		strconv.FormatBool(p.AllowBackorder),
+		p.FamilyID,
+	}
+}
@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Export(t *testing.T) {
+	router, _ := initRouter(t)
+
+	t.Run("products as csv", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=UTF-8", rec.Header().Get("Content-Type"))
+		exported, err := csv.NewReader(rec.Body).ReadAll()
+		assert.Nil(t, err)
+
+		f, err := os.Open("input/products.csv")
+		assert.Nil(t, err)
+		defer f.Close()
+		input, err := csv.NewReader(f).ReadAll()
+		assert.Nil(t, err)
+		// the family follows the columns of the input, which derives it from the product name
+		assert.Equal(t, append(input[0], "FamilyID"), exported[0])
+		var rows [][]string
+		for _, row := range exported[1:] {
+			assert.Equal(t, models.FamilyID(row[1]), row[len(row)-1])
+			rows = append(rows, row[:len(row)-1])
+		}
+		assert.ElementsMatch(t, input[1:], rows)
+	})
+
+	t.Run("families survive a round trip", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", adminKey, "ID,ProductName,Stock,Price,FamilyID\nNEW01,Sparkling Water,10,0.99,water\n"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export", customerKey))
+		exported := rec.Body.String()
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", adminKey, exported))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=sparkling", customerKey))
+		products := decodeProducts(t, rec)
+		if assert.Len(t, products, 1) {
+			assert.Equal(t, "water", products[0].FamilyID)
+		}
+	})
+
+	t.Run("products with the search filters", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?format=json&q=water&maxPrice=1.8&sort=-price&pageSize=1", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var exported []models.Product
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&exported))
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=water&maxPrice=1.8&sort=-price&pageSize=100", customerKey))
+		listed := decodeProducts(t, rec)
+		assert.Greater(t, len(listed), 1)
+		assert.Equal(t, listed, exported)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?sort=blablabla", customerKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("orders as json lines", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1}`))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/orders/export?format=jsonl&productId=MWBLU", adminKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		scanner := bufio.NewScanner(rec.Body)
+		var orders []models.Order
+		for scanner.Scan() {
+			var order models.Order
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &order))
+			orders = append(orders, order)
+		}
+		assert.Len(t, orders, 1)
+		assert.Equal(t, "MWBLU", orders[0].Item.ProductID)
+	})
+
+	t.Run("orders require an operator", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/orders/export", customerKey))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?format=blablabla", customerKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/orders-app/events"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+const (
+	// feedWriteWait is the time allowed to write a message to the client
+	feedWriteWait = 10 * time.Second
+	// feedPongWait is the time allowed to read the next pong from the client
+	feedPongWait = 60 * time.Second
+	// feedPingPeriod must be shorter than feedPongWait
+	feedPingPeriod = feedPongWait * 9 / 10
+	// feedMaxMessageSize is the largest message accepted from the client
+	feedMaxMessageSize = 4096
+)
+
+const (
+	feedMessage_Subscribe    = "subscribe"
+	feedMessage_Unsubscribe  = "unsubscribe"
+	feedMessage_Subscribed   = "subscribed"
+	feedMessage_Unsubscribed = "unsubscribed"
+	feedMessage_Event        = "event"
+	feedMessage_Error        = "error"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// feedRequest is a message sent by the client to change its subscriptions
+type feedRequest struct {
+	Type       string   `json:"type"`
+	OrderIDs   []string `json:"orderIds,omitempty"`
+	ProductIDs []string `json:"productIds,omitempty"`
+}
+
+// feedMessage is a message sent to the client
+type feedMessage struct {
+	Type       string        `json:"type"`
+	Event      *models.Event `json:"event,omitempty"`
+	OrderIDs   []string      `json:"orderIds,omitempty"`
+	ProductIDs []string      `json:"productIds,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// feedSubscriptions holds the orders and products a connection follows
+type feedSubscriptions struct {
+	orders   map[string]struct{}
+	products map[string]struct{}
+	lock     sync.Mutex
+}
+
+// apply adds or removes the subscriptions of the request and returns the resulting subscriptions
+func (s *feedSubscriptions) apply(req feedRequest) feedMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range req.OrderIDs {
+		if req.Type == feedMessage_Subscribe {
+			s.orders[id] = struct{}{}
+		} else {
+			delete(s.orders, id)
+		}
+	}
+	for _, id := range req.ProductIDs {
+		if req.Type == feedMessage_Subscribe {
+			s.products[id] = struct{}{}
+		} else {
+			delete(s.products, id)
+		}
+	}
+	reply := feedMessage{Type: feedMessage_Subscribed}
+	if req.Type == feedMessage_Unsubscribe {
+		reply.Type = feedMessage_Unsubscribed
+	}
+	for id := range s.orders {
+		reply.OrderIDs = append(reply.OrderIDs, id)
+	}
+	for id := range s.products {
+		reply.ProductIDs = append(reply.ProductIDs, id)
+	}
+	return reply
+}
+
+// matches checks whether the event concerns a followed order or product
+func (s *feedSubscriptions) matches(e models.Event) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.orders[e.OrderID]; ok && e.OrderID != "" {
+		return true
+	}
+	_, ok := s.products[e.ProductID]
+	return ok && e.ProductID != ""
+}
+
+// feedConn is a live feed WebSocket connection
+type feedConn struct {
+	conn    *websocket.Conn
+	subs    *feedSubscriptions
+	replies chan feedMessage
+	// closed is closed once the writer stopped
+	closed chan struct{}
+}
+
+// LiveFeed upgrades the connection to a WebSocket over which clients subscribe
+// to orders and products and receive their events as they happen
+func (h *handler) LiveFeed(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with an error
+		return
+	}
+	defer conn.Close()
+
+	// the connection filters events itself as its subscriptions change over time
+	sub := h.events.Subscribe(events.Filter{}, 0)
+	defer sub.Close()
+
+	c := &feedConn{
+		conn: conn,
+		subs: &feedSubscriptions{
+			orders:   make(map[string]struct{}),
+			products: make(map[string]struct{}),
+		},
+		replies: make(chan feedMessage, 16),
+		closed:  make(chan struct{}),
+	}
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		c.read()
+	}()
+	c.write(sub, readerDone)
+	close(c.closed)
+}
+
+// read handles the subscription requests of the client until the connection fails
+func (c *feedConn) read() {
+	c.conn.SetReadLimit(feedMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(feedPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(feedPongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req feedRequest
+		var reply feedMessage
+		switch err := json.Unmarshal(data, &req); {
+		case err != nil:
+			reply = feedMessage{Type: feedMessage_Error, Error: fmt.Sprintf("invalid message:%v", err)}
+		case req.Type == feedMessage_Subscribe || req.Type == feedMessage_Unsubscribe:
+			reply = c.subs.apply(req)
+		default:
+			reply = feedMessage{Type: feedMessage_Error, Error: "unknown message type " + req.Type}
+		}
+		select {
+		case c.replies <- reply:
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// write is the only writer of the connection, it sends replies, matching events and pings
+func (c *feedConn) write(sub *events.Subscription, readerDone <-chan struct{}) {
+	ping := time.NewTicker(feedPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case reply := <-c.replies:
+			if err := c.send(reply); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events:
+			if !ok {
+				// the client can not keep up, drop it rather than holding events back
+				logger.Log.Warn("Dropping slow live feed client")
+				_ = c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+					time.Now().Add(feedWriteWait))
+				return
+			}
+			if !c.subs.matches(e) {
+				continue
+			}
+			if err := c.send(feedMessage{Type: feedMessage_Event, Event: &e}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteWait)); err != nil {
+				return
+			}
+		case <-readerDone:
+			return
+		}
+	}
+}
+
+// send writes a message to the client, giving up after feedWriteWait
+func (c *feedConn) send(msg feedMessage) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(feedWriteWait))
+	return c.conn.WriteJSON(msg)
+}
@@ -0,0 +1,98 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/orders-app/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+type feedMessage struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"productIds"`
+	Error      string   `json:"error"`
+	Event      struct {
+		Type      string `json:"type"`
+		ProductID string `json:"productId"`
+	} `json:"event"`
+}
+
+func Test_LiveFeed(t *testing.T) {
+	router, _ := initRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("X-API-Key", customerKey)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/live", header)
+	assert.Nil(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg feedMessage
+	assert.Nil(t, conn.WriteJSON(map[string]any{"type": "subscribe", "productIds": []string{"MWORG"}}))
+	assert.Nil(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "subscribed", msg.Type)
+	assert.Equal(t, []string{"MWORG"}, msg.ProductIDs)
+
+	t.Run("events of subscribed products", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1}`))
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWORG","amount":1}`))
+
+		var received []string
+		for len(received) < 3 {
+			var msg feedMessage
+			assert.Nil(t, conn.ReadJSON(&msg))
+			assert.Equal(t, "event", msg.Type)
+			assert.Equal(t, "MWORG", msg.Event.ProductID)
+			received = append(received, msg.Event.Type)
+		}
+		assert.Equal(t, []string{"order.created", "stock.changed", "order.completed"}, received)
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		var msg feedMessage
+		assert.Nil(t, conn.WriteJSON(map[string]any{"type": "blablabla"}))
+		assert.Nil(t, conn.ReadJSON(&msg))
+		assert.Equal(t, "error", msg.Type)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		var msg feedMessage
+		assert.Nil(t, conn.WriteJSON(map[string]any{"type": "unsubscribe", "productIds": []string{"MWORG"}}))
+		assert.Nil(t, conn.ReadJSON(&msg))
+		assert.Equal(t, "unsubscribed", msg.Type)
+		assert.Empty(t, msg.ProductIDs)
+	})
+}
+
+func Test_LiveFeedQueryToken(t *testing.T) {
+	router, authenticator := initRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	token, err := authenticator.IssueToken(auth.Principal{Subject: "shop", Role: auth.Role_Customer}, time.Minute)
+	assert.Nil(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/live?access_token="+token, nil)
+	assert.Nil(t, err)
+	conn.Close()
+
+	t.Run("other routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/products?access_token="+token, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/live?access_token=blablabla", nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
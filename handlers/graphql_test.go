@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type graphqlResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func Test_GraphQL(t *testing.T) {
+	router, _ := initRouter(t)
+
+	t.Run("order with its product", func(t *testing.T) {
+		rec := graphqlRequest(router, customerKey, `mutation { createOrder(productId: "MWBLU", amount: 1) { id status } }`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		result := decodeResult(t, rec)
+		assert.Empty(t, result.Errors)
+		var created struct{ ID, Status string }
+		assert.Nil(t, json.Unmarshal(result.Data["createOrder"], &created))
+		assert.Equal(t, "New", created.Status)
+
+		rec = graphqlRequest(router, customerKey, `{ order(id: "`+created.ID+`") { id owner item { amount product { id stock } } } }`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		result = decodeResult(t, rec)
+		assert.Empty(t, result.Errors)
+		var order struct {
+			ID    string
+			Owner string
+			Item  struct {
+				Amount  int
+				Product struct {
+					ID    string
+					Stock int
+				}
+			}
+		}
+		assert.Nil(t, json.Unmarshal(result.Data["order"], &order))
+		assert.Equal(t, created.ID, order.ID)
+		assert.Equal(t, "customer", order.Owner)
+		assert.Equal(t, "MWBLU", order.Item.Product.ID)
+	})
+
+	t.Run("field requires a higher role", func(t *testing.T) {
+		rec := graphqlRequest(router, customerKey, `{ stats { revenue } }`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		result := decodeResult(t, rec)
+		assert.Len(t, result.Errors, 1)
+		assert.Equal(t, "FORBIDDEN", result.Errors[0].Extensions["code"])
+	})
+
+	t.Run("operator query", func(t *testing.T) {
+		rec := graphqlRequest(router, adminKey, `{ orders(productId: "MWBLU") { id } }`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, decodeResult(t, rec).Errors)
+	})
+
+	t.Run("stats roll-ups", func(t *testing.T) {
+		var stats struct {
+			CompletedOrders int
+			ByFamily        []struct{ Key string }
+			ByVariant       []struct {
+				Key    string
+				Totals struct{ CompletedOrders int }
+			}
+		}
+		// the statistics are processed and fetched with random delays, so they only show the order eventually
+		assert.Eventually(t, func() bool {
+			rec := graphqlRequest(router, adminKey, `{ stats { completedOrders byFamily { key } byVariant { key totals { completedOrders } } } }`)
+			result := decodeResult(t, rec)
+			return len(result.Errors) == 0 && json.Unmarshal(result.Data["stats"], &stats) == nil && stats.CompletedOrders == 1
+		}, 10*time.Second, 10*time.Millisecond)
+		assert.Len(t, stats.ByFamily, 1)
+		if assert.Len(t, stats.ByVariant, 1) {
+			assert.Equal(t, "MWBLU", stats.ByVariant[0].Key)
+			assert.Equal(t, 1, stats.ByVariant[0].Totals.CompletedOrders)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		rec := graphqlRequest(router, customerKey, `{ blablabla }`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.NotEmpty(t, decodeResult(t, rec).Errors)
+	})
+
+	t.Run("too complex", func(t *testing.T) {
+		fields := strings.Repeat("id name price stock ", 15)
+		query := `{ a: products { ` + fields + ` } b: products { ` + fields + ` } }`
+		rec := graphqlRequest(router, customerKey, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, decodeResult(t, rec).Errors[0].Message, "complexity")
+	})
+
+	t.Run("mutation over GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", `/v1/graphql?query=mutation%7BreverseOrder(id:%22x%22)%7Bid%7D%7D`, adminKey))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func Test_GraphQLRateLimit(t *testing.T) {
+	router, _ := initRouterWithLimits(t, map[string]config.RateLimit{
+		config.DefaultRateLimit: {Rate: 100, Burst: 100},
+		"orders":                {Rate: 0.5, Burst: 1},
+	})
+	mutation := `mutation { createOrder(productId: "MWBLU", amount: 1) { id } }`
+
+	result := decodeResult(t, graphqlRequest(router, customerKey, mutation))
+	assert.Empty(t, result.Errors)
+
+	// creating orders charges the orders policy, like POST /orders does
+	result = decodeResult(t, graphqlRequest(router, customerKey, mutation))
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "RATE_LIMITED", result.Errors[0].Extensions["code"])
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1}`))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// queries only charge the default policy
+	result = decodeResult(t, graphqlRequest(router, customerKey, `{ products { id } }`))
+	assert.Empty(t, result.Errors)
+}
+
+func graphqlRequest(router http.Handler, apiKey, query string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/graphql", apiKey, string(body)))
+	return rec
+}
+
+func decodeResult(t *testing.T, rec *httptest.ResponseRecorder) graphqlResult {
+	var result graphqlResult
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+	return result
+}
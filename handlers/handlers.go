@@ -3,37 +3,102 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/orders-app/alerts"
+	"github.com/orders-app/app"
+	"github.com/orders-app/config"
+	"github.com/orders-app/events"
+	"github.com/orders-app/graphqlapi"
+	"github.com/orders-app/metrics"
 	"github.com/orders-app/models"
 	"github.com/orders-app/repo"
+	"github.com/orders-app/webhooks"
 )
 
+// maxWait is the longest a client can wait for an order to be processed
+const maxWait = 30 * time.Second
+
 type handler struct {
-	repo repo.Repo
+	repo      repo.Repo
+	events    *events.Broker
+	heartbeat time.Duration
+	webhooks  *webhooks.Dispatcher
+	alerts    *alerts.Monitor
+	graphql   http.Handler
 }
 
 type Handler interface {
 	Index(w http.ResponseWriter, r *http.Request)
 	ProductIndex(w http.ResponseWriter, r *http.Request)
+	ProductExport(w http.ResponseWriter, r *http.Request)
+	ProductImport(w http.ResponseWriter, r *http.Request)
+	FamilyIndex(w http.ResponseWriter, r *http.Request)
+	FamilyShow(w http.ResponseWriter, r *http.Request)
+	OrderIndex(w http.ResponseWriter, r *http.Request)
+	OrderExport(w http.ResponseWriter, r *http.Request)
 	OrderShow(w http.ResponseWriter, r *http.Request)
 	OrderInsert(w http.ResponseWriter, r *http.Request)
+	OrderScheduledIndex(w http.ResponseWriter, r *http.Request)
+	OrderBatchInsert(w http.ResponseWriter, r *http.Request)
 	Close(w http.ResponseWriter, r *http.Request)
 	Open(w http.ResponseWriter, r *http.Request)
 	Stats(w http.ResponseWriter, r *http.Request)
 	OrderReverse(w http.ResponseWriter, r *http.Request)
+	OrderCancel(w http.ResponseWriter, r *http.Request)
+	OrderReturn(w http.ResponseWriter, r *http.Request)
+	OrderReturns(w http.ResponseWriter, r *http.Request)
+	Metrics(w http.ResponseWriter, r *http.Request)
+	EventStream(w http.ResponseWriter, r *http.Request)
+	LiveFeed(w http.ResponseWriter, r *http.Request)
+	WebhookInsert(w http.ResponseWriter, r *http.Request)
+	WebhookIndex(w http.ResponseWriter, r *http.Request)
+	WebhookDelete(w http.ResponseWriter, r *http.Request)
+	WebhookDeliveries(w http.ResponseWriter, r *http.Request)
+	DeadLetterIndex(w http.ResponseWriter, r *http.Request)
+	DeadLetterReplay(w http.ResponseWriter, r *http.Request)
+	GraphQL(w http.ResponseWriter, r *http.Request)
+	AlertIndex(w http.ResponseWriter, r *http.Request)
+	SupplierInsert(w http.ResponseWriter, r *http.Request)
+	SupplierIndex(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderInsert(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderIndex(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderShow(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderSend(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderCancel(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderReceive(w http.ResponseWriter, r *http.Request)
+	PurchaseOrderReceipts(w http.ResponseWriter, r *http.Request)
+	ReservationInsert(w http.ResponseWriter, r *http.Request)
+	ReservationIndex(w http.ResponseWriter, r *http.Request)
+	ReservationShow(w http.ResponseWriter, r *http.Request)
+	ReservationConfirm(w http.ResponseWriter, r *http.Request)
+	ReservationRelease(w http.ResponseWriter, r *http.Request)
+	SubscriptionInsert(w http.ResponseWriter, r *http.Request)
+	SubscriptionIndex(w http.ResponseWriter, r *http.Request)
+	SubscriptionShow(w http.ResponseWriter, r *http.Request)
+	SubscriptionUpdate(w http.ResponseWriter, r *http.Request)
+	SubscriptionCancel(w http.ResponseWriter, r *http.Request)
+	SubscriptionPause(w http.ResponseWriter, r *http.Request)
+	SubscriptionResume(w http.ResponseWriter, r *http.Request)
+	SubscriptionRuns(w http.ResponseWriter, r *http.Request)
 }
 
-func New() (Handler, error) {
-	r, err := repo.New()
-	if err != nil {
-		return nil, err
+// New creates the HTTP handlers of the app
+func New(a *app.App, cfg config.Events) Handler {
+	return &handler{
+		repo:      a.Repo,
+		events:    a.Events,
+		heartbeat: cfg.Heartbeat,
+		webhooks:  a.Webhooks,
+		alerts:    a.Alerts,
+		graphql:   graphqlapi.NewHandler(a.Repo),
 	}
-	h := handler{repo: r}
-	return &h, nil
 }
 
 // Index returns a simple hello response for the homepage
@@ -42,43 +107,159 @@ func (h *handler) Index(w http.ResponseWriter, r *http.Request) {
 	writeResponse(w, http.StatusOK, "Welcome to the Orders App!", nil)
 }
 
-// ProductIndex displays all products in the system
+// ProductIndex displays a page of the products matching the search query parameters, or all products grouped by family with ?groupBy=family
 func (h *handler) ProductIndex(w http.ResponseWriter, r *http.Request) {
-	p := h.repo.GetAllProducts()
-	// Send an HTTP status & send the slice
-	writeResponse(w, http.StatusOK, p, nil)
+	switch groupBy := r.URL.Query().Get("groupBy"); groupBy {
+	case "":
+	case "family":
+		writeResponse(w, http.StatusOK, h.repo.GetFamilies(), nil)
+		return
+	default:
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid groupBy %s, want family", groupBy))
+		return
+	}
+	query, err := parseProductQuery(r)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	page, err := h.repo.SearchProducts(query)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	// the data stays a plain list, the paging is described by headers
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	w.Header().Set("X-Page", strconv.Itoa(query.Page))
+	w.Header().Set("X-Page-Size", strconv.Itoa(query.PageSize))
+	writeResponse(w, http.StatusOK, page.Products, nil)
+}
+
+// FamilyIndex displays all product families with their variants
+func (h *handler) FamilyIndex(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, h.repo.GetFamilies(), nil)
+}
+
+// FamilyShow displays one product family with its variants
+func (h *handler) FamilyShow(w http.ResponseWriter, r *http.Request) {
+	family, err := h.repo.GetFamily(mux.Vars(r)["familyId"])
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, family, nil)
 }
 
-// OrderShow fetches and displays one selected product
+// OrderShow fetches and displays one selected order.
+// With the wait query parameter it waits up to the given duration for the order to be processed.
 func (h *handler) OrderShow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	orderId := vars["orderId"]
+	wait, err := parseWait(r)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
 	// Call the repository method corresponding to the operation
-	o, err := h.repo.GetOrder(orderId)
+	o, err := h.repo.WaitForOrder(ctx, orderId)
 	// Handle any errors & write an error HTTP status & response
 	if err != nil {
 		writeResponse(w, http.StatusNotFound, nil, err)
 		return
 	}
+	// the orders of other customers are not disclosed
+	if !owns(r, o.Owner) {
+		writeResponse(w, http.StatusNotFound, nil, fmt.Errorf("no order found for %s order id", orderId))
+		return
+	}
 	// Send an HTTP success status & the return value from the repo
 	writeResponse(w, http.StatusOK, o, nil)
 }
 
-// OrderInsert creates a new order with the given parameters
+// orderRequest is the body of a new order, an order with a processAt is placed at that time
+type orderRequest struct {
+	models.Item
+	ProcessAt *time.Time `json:"processAt"`
+}
+
+// OrderInsert accepts a new order with the given parameters,
+// the order is processed asynchronously and can be polled at its Location
 func (h *handler) OrderInsert(w http.ResponseWriter, r *http.Request) {
-	var item models.Item
+	var req orderRequest
 	// Read the request body
-	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid order body:%v", err))
 		return
 	}
-	order, err := h.repo.CreateOrder(item)
+	var order *models.Order
+	var err error
+	if req.ProcessAt != nil {
+		order, err = h.repo.ScheduleOrder(r.Context(), req.Item, *req.ProcessAt)
+	} else {
+		order, err = h.repo.CreateOrder(r.Context(), req.Item)
+	}
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, nil, err)
+		writeRepoError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeResponse(w, http.StatusOK, order, nil)
+	w.Header().Set("Location", path.Join(r.URL.Path, order.ID))
+	writeResponse(w, http.StatusAccepted, order, nil)
+}
+
+// OrderScheduledIndex displays the scheduled orders in the order they are placed
+func (h *handler) OrderScheduledIndex(w http.ResponseWriter, r *http.Request) {
+	orders := h.repo.GetScheduledOrders()
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	writeResponse(w, http.StatusOK, orders, nil)
+}
+
+// OrderIndex displays the orders matching the status and productId query parameters
+func (h *handler) OrderIndex(w http.ResponseWriter, r *http.Request) {
+	orders := h.repo.GetOrders(parseOrderFilter(r))
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	writeResponse(w, http.StatusOK, orders, nil)
+}
+
+// OrderBatchInsert accepts several orders at once and reports the outcome of each item.
+// With ?mode=atomic either all items are placed or none, by default every valid item is placed.
+func (h *handler) OrderBatchInsert(w http.ResponseWriter, r *http.Request) {
+	var atomic bool
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", "best-effort":
+	case "atomic":
+		atomic = true
+	default:
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid batch mode %s, want best-effort or atomic", mode))
+		return
+	}
+	var batch struct {
+		Items []models.Item `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid batch body:%v", err))
+		return
+	}
+	// every item costs as much as a single order
+	if !chargeRateLimit(w, r, config.OrdersRateLimit, len(batch.Items)) {
+		return
+	}
+	entries, err := h.repo.CreateOrders(r.Context(), batch.Items, atomic)
+	if errors.Is(err, repo.ErrBatchRejected) {
+		writeResponse(w, http.StatusUnprocessableEntity, entries, err)
+		return
+	}
+	if err != nil {
+		writeRepoError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeResponse(w, http.StatusAccepted, entries, nil)
 }
 
 func (h *handler) Open(w http.ResponseWriter, r *http.Request) {
@@ -116,11 +297,54 @@ func (h *handler) Stats(w http.ResponseWriter, r *http.Request) {
 func (h *handler) OrderReverse(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	orderId := vars["orderId"]
-	order, err := h.repo.RequestReversal(orderId)
+	order, err := h.repo.RequestReversal(r.Context(), orderId)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, nil, err)
+		writeRepoError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	writeResponse(w, http.StatusOK, order, nil)
 }
+
+// OrderCancel cancels an order which was not processed yet, customers only cancel their own orders
+func (h *handler) OrderCancel(w http.ResponseWriter, r *http.Request) {
+	orderId := mux.Vars(r)["orderId"]
+	order, err := h.repo.GetOrder(orderId)
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+	if !owns(r, order.Owner) {
+		writeResponse(w, http.StatusNotFound, nil, fmt.Errorf("no order found for %s order id", orderId))
+		return
+	}
+	order, err = h.repo.CancelOrder(orderId)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, order, nil)
+}
+
+// Metrics outputs the current value of all registered metrics
+func (h *handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, metrics.Snapshot(), nil)
+}
+
+// GraphQL resolves GraphQL queries and mutations, each field checks the role of the caller
+func (h *handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	h.graphql.ServeHTTP(w, r)
+}
+
+// parseWait reads the optional wait query parameter, capped at maxWait
+func parseWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("invalid wait duration %s", value)
+	}
+	return min(wait, maxWait), nil
+}
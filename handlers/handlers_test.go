@@ -0,0 +1,219 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orders-app/app"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/handlers"
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OrderInsert(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":500}`))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	order := decodeOrder(t, rec)
+	assert.Equal(t, string(models.OrderStatus_new), order.Status)
+	location := rec.Header().Get("Location")
+	assert.Equal(t, "/v1/orders/"+order.ID, location)
+
+	t.Run("wait for processing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", location+"?wait=2s", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, string(models.OrderStatus_Rejected), decodeOrder(t, rec).Status)
+	})
+
+	t.Run("invalid wait", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", location+"?wait=blablabla", customerKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid item", func(t *testing.T) {
+		for _, body := range []string{
+			`{"productId":"blablabla","amount":1}`,
+			`{"productId":"MWBLU","amount":0}`,
+		} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, body))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func Test_OrderBatchInsert(t *testing.T) {
+	router, _ := initRouter(t)
+	body := `{"items":[{"productId":"MWBLU","amount":1},{"productId":"blablabla","amount":1}]}`
+
+	t.Run("best effort", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch", customerKey, body))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		entries := decodeEntries(t, rec)
+		assert.Len(t, entries, 2)
+		assert.NotNil(t, entries[0].Order)
+		assert.NotEmpty(t, entries[1].Error)
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch?mode=atomic", customerKey, body))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		entries := decodeEntries(t, rec)
+		assert.Nil(t, entries[0].Order)
+		assert.NotEmpty(t, entries[0].Error)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch?mode=blablabla", customerKey, body))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func Test_OrderBatchRateLimit(t *testing.T) {
+	router, _ := initRouterWithLimits(t, map[string]config.RateLimit{
+		config.DefaultRateLimit: {Rate: 100, Burst: 100},
+		"orders":                {Rate: 0.5, Burst: 3},
+	})
+	body := `{"items":[{"productId":"MWBLU","amount":1},{"productId":"MWBLU","amount":1}]}`
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch", customerKey, body))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+
+	// every item is charged, so the second batch can not be afforded
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch", customerKey, body))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch", adminKey,
+		`{"items":[{"productId":"MWBLU","amount":1},{"productId":"MWBLU","amount":1},{"productId":"MWBLU","amount":1},{"productId":"MWBLU","amount":1}]}`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func Test_OrderBatchDefaultConfig(t *testing.T) {
+	t.Setenv("ORDERS_API_KEYS", "customer:customer:"+hashKey(customerKey))
+	cfg, err := config.Load()
+	assert.Nil(t, err)
+	a, err := app.New(cfg)
+	assert.Nil(t, err)
+	t.Cleanup(a.Close)
+	authenticator, err := auth.NewAuthenticator(cfg.Auth)
+	assert.Nil(t, err)
+	router := handlers.ConfigureHandler(handlers.New(a, cfg.Events), authenticator, cfg.RateLimits)
+
+	// the largest batch allowed can be afforded with the default rate limits
+	items := strings.TrimSuffix(strings.Repeat(`{"productId":"MWBLU","amount":1},`, cfg.Queue.MaxBatchSize), ",")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders/batch", customerKey, `{"items":[`+items+`]}`))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	for _, entry := range decodeEntries(t, rec) {
+		assert.NotNil(t, entry.Order)
+	}
+
+	t.Run("batch size above the orders burst", func(t *testing.T) {
+		t.Setenv("ORDERS_BATCH_MAX_SIZE", "50")
+		_, err := config.Load()
+		assert.NotNil(t, err)
+	})
+}
+
+func decodeEntries(t *testing.T, rec *httptest.ResponseRecorder) []models.BatchEntry {
+	var resp struct {
+		Data []models.BatchEntry `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
+
+func decodeOrder(t *testing.T, rec *httptest.ResponseRecorder) models.Order {
+	var resp struct {
+		Data models.Order `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
+
+func Test_OrderCancel(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1}`))
+	location := rec.Header().Get("Location")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", location+"?wait=2s", customerKey))
+	assert.Equal(t, string(models.OrderStatus_Completed), decodeOrder(t, rec).Status)
+
+	t.Run("processed order", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", location+"/cancel", customerKey))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", "/v1/orders/blablabla/cancel", customerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("order of another customer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1,"processAt":"2099-01-01T00:00:00Z"}`))
+		location := rec.Header().Get("Location")
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", location+"/cancel", otherCustomerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", location, otherCustomerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		// operators act on the orders of every customer
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", location+"/cancel", adminKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "customer", decodeOrder(t, rec).Owner)
+	})
+}
+
+func Test_ScheduledOrders(t *testing.T) {
+	router, _ := initRouter(t)
+
+	processAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1,"processAt":"`+processAt+`"}`))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	order := decodeOrder(t, rec)
+	assert.Equal(t, string(models.OrderStatus_Scheduled), order.Status)
+	assert.NotEmpty(t, order.ProcessAt)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", "/v1/orders/scheduled", adminKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var scheduled struct {
+		Data []models.Order `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&scheduled))
+	assert.Len(t, scheduled.Data, 1)
+
+	t.Run("invalid time", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":1,"processAt":"tomorrow"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
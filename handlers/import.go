@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/utils"
+)
+
+// maxImportSize is the largest product upload accepted
+const maxImportSize = 10 << 20
+
+// ProductImport imports products uploaded as CSV or JSON, either as the request body or as the file field of a form.
+// The mode query parameter selects upsert (the default), replace or dry-run. Nothing is applied when a line is invalid.
+func (h *handler) ProductImport(w http.ResponseWriter, r *http.Request) {
+	mode := models.ImportMode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = models.ImportMode_Upsert
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body, format, err := importUpload(r)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	defer body.Close()
+
+	var products []models.Product
+	switch format {
+	case "json":
+		products, err = utils.ParseProductsJSON(body)
+	default:
+		products, err = utils.ParseProductsCSV(body)
+	}
+	var importErrs utils.ImportErrors
+	if errors.As(err, &importErrs) {
+		writeResponse(w, http.StatusUnprocessableEntity, models.ImportReport{Mode: mode, Errors: importErrs}, err)
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid product upload:%v", err))
+		return
+	}
+
+	report, err := h.repo.ImportProducts(products, mode)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, report, nil)
+}
+
+// importUpload returns the uploaded file and its format, json or csv
+func importUpload(r *http.Request) (io.ReadCloser, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid product upload:%v", err)
+		}
+		if filepath.Ext(header.Filename) == ".json" {
+			return file, "json", nil
+		}
+		return file, "csv", nil
+	case "application/json":
+		return r.Body, "json", nil
+	case "", "text/csv":
+		return r.Body, "csv", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported content type %s, want text/csv, application/json or multipart/form-data", mediaType)
+	}
+}
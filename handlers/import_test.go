@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProductImport(t *testing.T) {
+	router, _ := initRouter(t)
+	csv := "ID,ProductName,Stock,Variety,Price\nMWBLU,Mineral Water,5,Blueberry,2.10\nNEW01,Sparkling Water,10,Plain,0.99\n"
+
+	t.Run("dry run", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import?mode=dry-run", adminKey, csv))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		report := decodeReport(t, rec)
+		assert.Equal(t, []string{"NEW01"}, report.Created)
+		assert.Equal(t, []string{"MWBLU"}, report.Updated)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?format=json", customerKey))
+		assert.NotContains(t, rec.Body.String(), "NEW01")
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", adminKey, csv))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, decodeReport(t, rec).Removed)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?format=json", customerKey))
+		var products []models.Product
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&products))
+		assert.Contains(t, products, models.Product{ID: "NEW01", FamilyID: "sparkling-water", Name: "Sparkling Water", Variety: "Plain", Stock: 10, Price: 0.99})
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		req := newBodyRequest("POST", "/v1/products/import?mode=replace", adminKey, `[{"id":"MWBLU","name":"Mineral Water","variety":"Blueberry","price":2.1,"stock":5}]`)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		report := decodeReport(t, rec)
+		assert.Contains(t, report.Removed, "NEW01")
+		assert.Empty(t, report.Updated)
+	})
+
+	t.Run("replace keeps reserved stock", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", adminKey, "ID,ProductName,Stock,Price\nNEW02,Still Water,10,0.89\n"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/reservations", customerKey, `{"productId":"NEW02","amount":2,"ttl":"5m"}`))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import?mode=replace", adminKey, "ID,ProductName,Stock,Variety,Price\nMWBLU,Mineral Water,5,Blueberry,2.10\n"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		report := decodeReport(t, rec)
+		assert.Equal(t, []string{"NEW02"}, report.Kept)
+		assert.NotContains(t, report.Removed, "NEW02")
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products/export?format=json", customerKey))
+		assert.Contains(t, rec.Body.String(), "NEW02")
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", adminKey, "ID,ProductName,Stock,Price\nX,,1,1\n"))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, []models.ImportError{{Line: 2, Column: "ProductName", Reason: "must not be empty"}}, decodeReport(t, rec).Errors)
+	})
+
+	t.Run("requires an admin", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/products/import", customerKey, csv))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func decodeReport(t *testing.T, rec *httptest.ResponseRecorder) models.ImportReport {
+	var resp struct {
+		Data models.ImportReport `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
@@ -1,32 +1,225 @@
 package handlers
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
-	"go.opentelemetry.io/otel"
+	"github.com/gorilla/websocket"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/ratelimit"
+	"github.com/orders-app/tracing"
 	"go.opentelemetry.io/otel/attribute"
 )
 
 func OpenTelemetryMiddleware(serviceName string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get the tracer
-			tracer := otel.Tracer(serviceName)
-
 			// Start a new span for the incoming request
-			ctx, span := tracer.Start(r.Context(), r.URL.Path)
-			defer span.End()
-
-			// Add attributes to the span
-			span.SetAttributes(
+			ctx, span := tracing.StartSpan(r.Context(), serviceName, r.URL.Path,
 				attribute.String("http.method", r.Method),
-				attribute.String("http.url", r.URL.String()),
+				attribute.String("http.url", redactedURL(r)),
 				attribute.String("http.client_ip", r.RemoteAddr),
 			)
+			defer span.End()
 
 			// Pass the context with the span to the next handler
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// redactedURL is the URL of the request without its query, which may carry credentials such as an access token
+func redactedURL(r *http.Request) string {
+	u := *r.URL
+	u.RawQuery = ""
+	u.ForceQuery = false
+	return u.String()
+}
+
+// DeprecationMiddleware flags responses of deprecated routes and points clients to the successor version
+func DeprecationMiddleware(sunset time.Time, successorPrefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successorPrefix, r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthMiddleware resolves the principal of the request and stores it in the request context.
+// Requests without credentials are passed on anonymously, it is up to the route to require a role.
+func AuthMiddleware(authenticator *auth.Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="orders-app"`)
+				writeResponse(w, http.StatusUnauthorized, nil, fmt.Errorf("authentication failed:%v", err))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// queryTokenAuth authenticates WebSocket upgrades without credentials in their headers by the access_token
+// query parameter, as browsers can not set headers on WebSocket connections
+func queryTokenAuth(authenticator *auth.Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); ok || !websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, err := authenticator.AuthenticateQueryToken(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orders-app"`)
+			writeResponse(w, http.StatusUnauthorized, nil, fmt.Errorf("authentication failed:%v", err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// requireRole only lets requests through whose principal holds at least the given role
+func requireRole(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := auth.Authorize(r.Context(), role)
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orders-app"`)
+			writeResponse(w, http.StatusUnauthorized, nil, err)
+			return
+		}
+		if err != nil {
+			writeResponse(w, http.StatusForbidden, nil, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// owns checks whether the caller of the request may act on a resource of the given owner,
+// operators and admins act on the resources of every caller
+func owns(r *http.Request, owner string) bool {
+	principal, ok := auth.FromContext(r.Context())
+	return ok && (principal.Role.Allows(auth.Role_Operator) || (owner != "" && principal.Subject == owner))
+}
+
+// rateLimit rejects requests of clients which exhausted the rate limit of the policy with a 429.
+// Handlers which cost more than one request charge the other policies through the request context.
+func rateLimit(limiters map[string]*ratelimit.Limiter, policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := clientKey(r)
+		if !allowed(w, limiterFor(limiters, policy).Allow(key)) {
+			return
+		}
+		charge := ratelimit.Charge(func(policy string, cost int) ratelimit.Result {
+			return limiterFor(limiters, policy).AllowN(key, cost)
+		})
+		next.ServeHTTP(w, r.WithContext(ratelimit.WithCharge(r.Context(), charge)))
+	})
+}
+
+// chargeRateLimit takes cost tokens of the policy from the caller of the request,
+// it writes the error response and returns false when the caller can not afford them
+func chargeRateLimit(w http.ResponseWriter, r *http.Request, policy string, cost int) bool {
+	charge, ok := ratelimit.ChargeFromContext(r.Context())
+	if !ok {
+		return true
+	}
+	res := charge(policy, cost)
+	if !res.Allowed && cost > res.Limit {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("request costs %d requests of the %s rate limit, more than its burst of %d", cost, policy, res.Limit))
+		return false
+	}
+	return allowed(w, res)
+}
+
+// allowed sets the rate limit headers of the response and writes a 429 when the request was not allowed
+func allowed(w http.ResponseWriter, res ratelimit.Result) bool {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		writeResponse(w, http.StatusTooManyRequests, nil, errors.New("rate limit exceeded, please try again later"))
+		return false
+	}
+	return true
+}
+
+// clientKey identifies the caller by its principal, or by its IP address for anonymous requests
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + principal.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps the event stream working behind the recorder
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack keeps the live feed WebSocket upgrade working behind the recorder
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking is not supported")
+	}
+	s.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// LoggingMiddleware logs every handled request with its status, duration and principal
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		principal, _ := auth.FromContext(r.Context())
+		logger.LogRequest("http", r.Method+" "+r.URL.Path, strconv.Itoa(rec.status), time.Since(start), principal.Subject)
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/orders-app/models"
+)
+
+// page sizes of product listings
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseProductQuery reads the product search from the q, category, tag, inStock, minPrice, maxPrice, sort,
+// page and pageSize query parameters
+func parseProductQuery(r *http.Request) (models.ProductQuery, error) {
+	values := r.URL.Query()
+	query := models.ProductQuery{
+		Text:     values.Get("q"),
+		Category: values.Get("category"),
+		Tag:      values.Get("tag"),
+		Sort:     values.Get("sort"),
+		Page:     1,
+		PageSize: defaultPageSize,
+	}
+	var err error
+	if value := values.Get("inStock"); value != "" {
+		if query.InStock, err = strconv.ParseBool(value); err != nil {
+			return query, fmt.Errorf("invalid inStock %s, want true or false", value)
+		}
+	}
+	for name, target := range map[string]*float64{"minPrice": &query.MinPrice, "maxPrice": &query.MaxPrice} {
+		if value := values.Get(name); value != "" {
+			if *target, err = strconv.ParseFloat(value, 64); err != nil || *target < 0 {
+				return query, fmt.Errorf("invalid %s %s, want a positive number", name, value)
+			}
+		}
+	}
+	for name, target := range map[string]*int{"page": &query.Page, "pageSize": &query.PageSize} {
+		if value := values.Get(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil || *target < 1 {
+				return query, fmt.Errorf("invalid %s %s, want a whole number of at least 1", name, value)
+			}
+		}
+	}
+	query.PageSize = min(query.PageSize, maxPageSize)
+	return query, nil
+}
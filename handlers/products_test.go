@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Families(t *testing.T) {
+	router, _ := initRouter(t)
+
+	t.Run("products grouped by family", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?groupBy=family", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		families := decodeFamilies(t, rec)
+		assert.Len(t, families, 1)
+		assert.Equal(t, "mineral-water", families[0].ID)
+		assert.Len(t, families[0].Variants, 8)
+	})
+
+	t.Run("family", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/families/mineral-water", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unknown family", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/families/blablabla", customerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func Test_ProductSearch(t *testing.T) {
+	router, _ := initRouter(t)
+
+	t.Run("text, tag and price", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=miner+wat&tag=sparkling&maxPrice=1.80&sort=-price", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		products := decodeProducts(t, rec)
+		assert.Len(t, products, 3)
+		assert.Equal(t, []string{"MWBLU", "MWRAS", "MWLEM"}, []string{products[0].ID, products[1].ID, products[2].ID})
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?category=drinks&sort=price&page=2&pageSize=3", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "8", rec.Header().Get("X-Total-Count"))
+		products := decodeProducts(t, rec)
+		assert.Len(t, products, 3)
+		assert.Equal(t, "MWBLU", products[0].ID)
+	})
+
+	t.Run("variety", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=lemon", customerKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		products := decodeProducts(t, rec)
+		assert.Len(t, products, 1)
+		assert.Equal(t, "MWLEM", products[0].ID)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/products?sort=blablabla", customerKey))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func decodeProducts(t *testing.T, rec *httptest.ResponseRecorder) []models.Product {
+	var resp struct {
+		Data []models.Product `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
+
+func decodeFamilies(t *testing.T, rec *httptest.ResponseRecorder) []models.Family {
+	var resp struct {
+		Data []models.Family `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/models"
+)
+
+// supplierRequest is the body of a new supplier
+type supplierRequest struct {
+	Name       string   `json:"name"`
+	Email      string   `json:"email"`
+	ProductIDs []string `json:"productIds"`
+}
+
+// purchaseOrderRequest is the body of a new purchase order
+type purchaseOrderRequest struct {
+	SupplierID string                `json:"supplierId"`
+	Lines      []models.PurchaseLine `json:"lines"`
+}
+
+// receiptRequest is the body of goods received for a purchase order
+type receiptRequest struct {
+	Lines []models.PurchaseLine `json:"lines"`
+}
+
+// SupplierInsert creates a new supplier
+func (h *handler) SupplierInsert(w http.ResponseWriter, r *http.Request) {
+	var req supplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid supplier body:%v", err))
+		return
+	}
+	supplier, err := h.repo.CreateSupplier(req.Name, req.Email, req.ProductIDs)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, supplier, nil)
+}
+
+// SupplierIndex displays all suppliers
+func (h *handler) SupplierIndex(w http.ResponseWriter, r *http.Request) {
+	suppliers := h.repo.GetSuppliers()
+	if suppliers == nil {
+		suppliers = []models.Supplier{}
+	}
+	writeResponse(w, http.StatusOK, suppliers, nil)
+}
+
+// PurchaseOrderInsert creates a draft purchase order
+func (h *handler) PurchaseOrderInsert(w http.ResponseWriter, r *http.Request) {
+	var req purchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid purchase order body:%v", err))
+		return
+	}
+	po, err := h.repo.CreatePurchaseOrder(req.SupplierID, req.Lines)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, po, nil)
+}
+
+// PurchaseOrderIndex displays the purchase orders, filtered by the status query parameter
+func (h *handler) PurchaseOrderIndex(w http.ResponseWriter, r *http.Request) {
+	pos := h.repo.GetPurchaseOrders(models.PurchaseOrderStatus(r.URL.Query().Get("status")))
+	if pos == nil {
+		pos = []models.PurchaseOrder{}
+	}
+	writeResponse(w, http.StatusOK, pos, nil)
+}
+
+// PurchaseOrderShow displays one purchase order
+func (h *handler) PurchaseOrderShow(w http.ResponseWriter, r *http.Request) {
+	po, err := h.repo.GetPurchaseOrder(mux.Vars(r)["purchaseOrderId"])
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, po, nil)
+}
+
+// PurchaseOrderSend marks a draft purchase order as sent to the supplier
+func (h *handler) PurchaseOrderSend(w http.ResponseWriter, r *http.Request) {
+	h.updatePurchaseOrderStatus(w, r, models.PurchaseOrderStatus_Sent)
+}
+
+// PurchaseOrderCancel cancels a purchase order which did not receive any goods
+func (h *handler) PurchaseOrderCancel(w http.ResponseWriter, r *http.Request) {
+	h.updatePurchaseOrderStatus(w, r, models.PurchaseOrderStatus_Cancelled)
+}
+
+func (h *handler) updatePurchaseOrderStatus(w http.ResponseWriter, r *http.Request, status models.PurchaseOrderStatus) {
+	po, err := h.repo.UpdatePurchaseOrderStatus(mux.Vars(r)["purchaseOrderId"], status)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, po, nil)
+}
+
+// PurchaseOrderReceive receives goods of a purchase order, restocking its products
+func (h *handler) PurchaseOrderReceive(w http.ResponseWriter, r *http.Request) {
+	var req receiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid receipt body:%v", err))
+		return
+	}
+	principal, _ := auth.FromContext(r.Context())
+	po, err := h.repo.ReceivePurchaseOrder(mux.Vars(r)["purchaseOrderId"], req.Lines, principal.Subject)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, po, nil)
+}
+
+// PurchaseOrderReceipts displays the audit of the goods received for a purchase order
+func (h *handler) PurchaseOrderReceipts(w http.ResponseWriter, r *http.Request) {
+	receipts, err := h.repo.GetStockReceipts(mux.Vars(r)["purchaseOrderId"])
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, receipts, nil)
+}
@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PurchaseOrderWorkflow(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/suppliers", adminKey, `{"name":"Springs Ltd","productIds":["MWBLU"]}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var supplier struct {
+		Data models.Supplier `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&supplier))
+
+	rec = httptest.NewRecorder()
+	body := fmt.Sprintf(`{"supplierId":%q,"lines":[{"productId":"MWBLU","quantity":10}]}`, supplier.Data.ID)
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/purchase-orders", adminKey, body))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	po := decodePurchaseOrder(t, rec)
+	location := "/v1/purchase-orders/" + po.ID
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("POST", location+"/send", adminKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, models.PurchaseOrderStatus_Sent, decodePurchaseOrder(t, rec).Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", location+"/receipts", adminKey, `{"lines":[{"productId":"MWBLU","quantity":10}]}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, models.PurchaseOrderStatus_Received, decodePurchaseOrder(t, rec).Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=blueberry", customerKey))
+	assert.Equal(t, 30, decodeProducts(t, rec)[0].Stock)
+
+	t.Run("receipts", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", location+"/receipts", adminKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var receipts struct {
+			Data []models.StockReceipt `json:"data"`
+		}
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&receipts))
+		assert.Len(t, receipts.Data, 1)
+		assert.Equal(t, "admin", receipts.Data[0].ReceivedBy)
+	})
+
+	t.Run("cancelling a received purchase order", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", location+"/cancel", adminKey))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("unknown purchase order", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/purchase-orders/blablabla", adminKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("unknown supplier", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/purchase-orders", adminKey, `{"supplierId":"blablabla","lines":[{"productId":"MWBLU","quantity":1}]}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func decodePurchaseOrder(t *testing.T, rec *httptest.ResponseRecorder) models.PurchaseOrder {
+	var resp struct {
+		Data models.PurchaseOrder `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
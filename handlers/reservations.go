@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// reservationRequest is the body of a new reservation, the ttl is a duration such as 10m
+type reservationRequest struct {
+	models.Item
+	TTL string `json:"ttl"`
+}
+
+// ReservationInsert holds stock of a product until the reservation is confirmed, released or expires
+func (h *handler) ReservationInsert(w http.ResponseWriter, r *http.Request) {
+	var req reservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid reservation body:%v", err))
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid ttl %s", req.TTL))
+			return
+		}
+	}
+	reservation, err := h.repo.Reserve(r.Context(), req.Item, ttl)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", path.Join(r.URL.Path, reservation.ID))
+	writeResponse(w, http.StatusCreated, reservation, nil)
+}
+
+// ReservationIndex displays the reservations, filtered by the status query parameter
+func (h *handler) ReservationIndex(w http.ResponseWriter, r *http.Request) {
+	reservations := h.repo.GetReservations(models.ReservationStatus(r.URL.Query().Get("status")))
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+	writeResponse(w, http.StatusOK, reservations, nil)
+}
+
+// ReservationShow displays one reservation of the caller
+func (h *handler) ReservationShow(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.ownReservation(w, r)
+	if !ok {
+		return
+	}
+	writeResponse(w, http.StatusOK, reservation, nil)
+}
+
+// ReservationConfirm places the order of a reservation of the caller, taking the reserved stock
+func (h *handler) ReservationConfirm(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.ownReservation(w, r)
+	if !ok {
+		return
+	}
+	order, err := h.repo.ConfirmReservation(r.Context(), reservation.ID)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the order lives next to the reservations, under the same API version
+	w.Header().Set("Location", path.Join(path.Dir(path.Dir(path.Dir(r.URL.Path))), "orders", order.ID))
+	writeResponse(w, http.StatusAccepted, order, nil)
+}
+
+// ReservationRelease returns the stock of a reservation of the caller
+func (h *handler) ReservationRelease(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.ownReservation(w, r)
+	if !ok {
+		return
+	}
+	reservation, err := h.repo.ReleaseReservation(reservation.ID)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, reservation, nil)
+}
+
+// ownReservation returns the reservation of the request, it writes a 404 when it does not exist or belongs
+// to another customer, so the reservations of other customers are not disclosed
+func (h *handler) ownReservation(w http.ResponseWriter, r *http.Request) (models.Reservation, bool) {
+	id := mux.Vars(r)["reservationId"]
+	reservation, err := h.repo.GetReservation(id)
+	if err == nil && !owns(r, reservation.Owner) {
+		err = fmt.Errorf("%w: no reservation found for id %s", repo.ErrNotFound, id)
+	}
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return models.Reservation{}, false
+	}
+	return reservation, true
+}
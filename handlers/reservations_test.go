@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reservations(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/reservations", customerKey, `{"productId":"MWBLU","amount":2,"ttl":"5m"}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	reservation := decodeReservation(t, rec)
+	assert.Equal(t, models.ReservationStatus_Active, reservation.Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", "/v1/products?q=blueberry", customerKey))
+	product := decodeProducts(t, rec)[0]
+	assert.Equal(t, 18, product.Stock)
+	assert.Equal(t, 2, product.Reserved)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("POST", "/v1/reservations/"+reservation.ID+"/confirm", customerKey))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	order := decodeOrder(t, rec)
+	assert.Equal(t, "/v1/orders/"+order.ID, rec.Header().Get("Location"))
+
+	t.Run("release a confirmed reservation", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", "/v1/reservations/"+reservation.ID+"/release", customerKey))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("reservation of another customer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/reservations", customerKey, `{"productId":"MWBLU","amount":1}`))
+		held := decodeReservation(t, rec)
+		assert.Equal(t, "customer", held.Owner)
+
+		for _, req := range []*http.Request{
+			newRequest("GET", "/v1/reservations/"+held.ID, otherCustomerKey),
+			newRequest("POST", "/v1/reservations/"+held.ID+"/confirm", otherCustomerKey),
+			newRequest("POST", "/v1/reservations/"+held.ID+"/release", otherCustomerKey),
+		} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+
+		// the order of the reservation belongs to its owner as well
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", "/v1/reservations/"+held.ID+"/confirm", customerKey))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "customer", decodeOrder(t, rec).Owner)
+	})
+
+	t.Run("unknown reservation", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/reservations/blablabla", customerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/reservations", customerKey, `{"productId":"MWBLU","amount":2,"ttl":"blablabla"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func decodeReservation(t *testing.T, rec *httptest.ResponseRecorder) models.Reservation {
+	var resp struct {
+		Data models.Reservation `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+
+	"github.com/orders-app/repo"
 )
 
+// unavailableRetryAfter is the number of seconds clients are asked to wait when the app is unavailable
+const unavailableRetryAfter = 1
+
 type Response struct {
 	Data  interface{} `json:"data,omitempty"`
 	Error string      `json:"error,omitempty"`
@@ -27,3 +34,20 @@ func writeResponse(w http.ResponseWriter, status int, data interface{}, err erro
 		fmt.Fprintf(w, "error encoding resp %v:%s", resp, err)
 	}
 }
+
+// writeRepoError writes a repo error with its matching HTTP status, or the fallback status for other errors
+func writeRepoError(w http.ResponseWriter, fallback int, err error) {
+	switch {
+	case errors.Is(err, repo.ErrOverloaded) || errors.Is(err, repo.ErrClosed):
+		w.Header().Set("Retry-After", strconv.Itoa(unavailableRetryAfter))
+		writeResponse(w, http.StatusServiceUnavailable, nil, err)
+	case errors.Is(err, repo.ErrNotFound):
+		writeResponse(w, http.StatusNotFound, nil, err)
+	case errors.Is(err, repo.ErrInvalid):
+		writeResponse(w, http.StatusBadRequest, nil, err)
+	case errors.Is(err, repo.ErrConflict):
+		writeResponse(w, http.StatusConflict, nil, err)
+	default:
+		writeResponse(w, fallback, nil, err)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/models"
+)
+
+// returnRequest is the body of a return, either the quantity of the ordered product or the quantities of lines
+type returnRequest struct {
+	Quantity int                 `json:"quantity"`
+	Lines    []models.ReturnLine `json:"lines"`
+}
+
+// OrderReturn returns part of a completed order, restocking the returned quantity
+func (h *handler) OrderReturn(w http.ResponseWriter, r *http.Request) {
+	var req returnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid return body:%v", err))
+		return
+	}
+	lines := req.Lines
+	if req.Quantity != 0 {
+		if len(lines) > 0 {
+			writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("return must contain either a quantity or lines"))
+			return
+		}
+		lines = []models.ReturnLine{{Quantity: req.Quantity}}
+	}
+	ret, err := h.repo.ReturnOrder(mux.Vars(r)["orderId"], lines)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, ret, nil)
+}
+
+// OrderReturns displays the returns of an order
+func (h *handler) OrderReturns(w http.ResponseWriter, r *http.Request) {
+	returns, err := h.repo.GetReturns(mux.Vars(r)["orderId"])
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, returns, nil)
+}
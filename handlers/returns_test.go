@@ -0,0 +1,66 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OrderReturns(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/orders", customerKey, `{"productId":"MWBLU","amount":5}`))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	location := rec.Header().Get("Location")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", location+"?wait=2s", customerKey))
+	order := decodeOrder(t, rec)
+	assert.Equal(t, string(models.OrderStatus_Completed), order.Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", location+"/returns", adminKey, `{"quantity":2}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		Data models.Return `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.Equal(t, 3.58, created.Data.Refund)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", location, customerKey))
+	order = decodeOrder(t, rec)
+	assert.Equal(t, 2, order.Returned)
+	assert.Equal(t, 3.58, order.Refunded)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", location+"/returns", adminKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var returns struct {
+		Data []models.Return `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&returns))
+	assert.Len(t, returns.Data, 1)
+
+	t.Run("over return", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", location+"/returns", adminKey, `{"lines":[{"productId":"MWBLU","quantity":4}]}`))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("quantity and lines", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", location+"/returns", adminKey, `{"quantity":1,"lines":[{"quantity":1}]}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("customers can not return", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", location+"/returns", customerKey, `{"quantity":1}`))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// subscriptionRequest is the body of a new subscription, an empty start time places the first order right away
+type subscriptionRequest struct {
+	models.Item
+	Cadence models.Cadence `json:"cadence"`
+	StartAt *time.Time     `json:"startAt"`
+}
+
+// subscriptionUpdate is the body of a subscription change, omitted fields are kept
+type subscriptionUpdate struct {
+	Amount  int            `json:"amount"`
+	Cadence models.Cadence `json:"cadence"`
+}
+
+// SubscriptionInsert creates a new subscription placing an order at a regular cadence
+func (h *handler) SubscriptionInsert(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid subscription body:%v", err))
+		return
+	}
+	var startAt time.Time
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	subscription, err := h.repo.CreateSubscription(r.Context(), req.Item, req.Cadence, startAt)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", path.Join(r.URL.Path, subscription.ID))
+	writeResponse(w, http.StatusCreated, subscription, nil)
+}
+
+// SubscriptionIndex displays the subscriptions, filtered by the status query parameter
+func (h *handler) SubscriptionIndex(w http.ResponseWriter, r *http.Request) {
+	subscriptions := h.repo.GetSubscriptions(models.SubscriptionStatus(r.URL.Query().Get("status")))
+	if subscriptions == nil {
+		subscriptions = []models.Subscription{}
+	}
+	writeResponse(w, http.StatusOK, subscriptions, nil)
+}
+
+// SubscriptionShow displays one subscription of the caller
+func (h *handler) SubscriptionShow(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	writeResponse(w, http.StatusOK, subscription, nil)
+}
+
+// SubscriptionUpdate changes the amount or the cadence of a subscription of the caller
+func (h *handler) SubscriptionUpdate(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid subscription body:%v", err))
+		return
+	}
+	subscription, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	subscription, err := h.repo.UpdateSubscription(subscription.ID, req.Amount, req.Cadence)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, subscription, nil)
+}
+
+// SubscriptionPause stops a subscription from placing orders until it is resumed
+func (h *handler) SubscriptionPause(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscriptionStatus(w, r, models.SubscriptionStatus_Paused)
+}
+
+// SubscriptionResume places the orders of a paused subscription again, starting with its next cycle
+func (h *handler) SubscriptionResume(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscriptionStatus(w, r, models.SubscriptionStatus_Active)
+}
+
+// SubscriptionCancel stops a subscription for good
+func (h *handler) SubscriptionCancel(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscriptionStatus(w, r, models.SubscriptionStatus_Cancelled)
+}
+
+// SubscriptionRuns displays the orders placed by the cycles of a subscription of the caller and why they failed
+func (h *handler) SubscriptionRuns(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	runs, err := h.repo.GetSubscriptionRuns(subscription.ID)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, runs, nil)
+}
+
+func (h *handler) updateSubscriptionStatus(w http.ResponseWriter, r *http.Request, status models.SubscriptionStatus) {
+	subscription, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	subscription, err := h.repo.UpdateSubscriptionStatus(subscription.ID, status)
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, subscription, nil)
+}
+
+// ownSubscription returns the subscription of the request, it writes a 404 when it does not exist or belongs
+// to another customer, so the subscriptions of other customers are not disclosed
+func (h *handler) ownSubscription(w http.ResponseWriter, r *http.Request) (models.Subscription, bool) {
+	id := mux.Vars(r)["subscriptionId"]
+	subscription, err := h.repo.GetSubscription(id)
+	if err == nil && !owns(r, subscription.Owner) {
+		err = fmt.Errorf("%w: no subscription found for id %s", repo.ErrNotFound, id)
+	}
+	if err != nil {
+		writeRepoError(w, http.StatusInternalServerError, err)
+		return models.Subscription{}, false
+	}
+	return subscription, true
+}
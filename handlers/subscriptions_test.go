@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Subscriptions(t *testing.T) {
+	router, _ := initRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("POST", "/v1/subscriptions", customerKey, `{"productId":"MWBLU","amount":2,"cadence":"weekly"}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	subscription := decodeSubscription(t, rec)
+	location := rec.Header().Get("Location")
+	assert.Equal(t, "/v1/subscriptions/"+subscription.ID, location)
+	assert.Equal(t, models.SubscriptionStatus_Active, subscription.Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newBodyRequest("PATCH", location, customerKey, `{"amount":3}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 3, decodeSubscription(t, rec).Item.Amount)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("POST", location+"/pause", customerKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, models.SubscriptionStatus_Paused, decodeSubscription(t, rec).Status)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("GET", location+"/runs", customerKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest("DELETE", location, customerKey))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, models.SubscriptionStatus_Cancelled, decodeSubscription(t, rec).Status)
+
+	t.Run("resume a cancelled subscription", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("POST", location+"/resume", customerKey))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/subscriptions?status=cancelled", adminKey))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp struct {
+			Data []models.Subscription `json:"data"`
+		}
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Len(t, resp.Data, 1)
+	})
+
+	t.Run("subscription of another customer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/subscriptions", customerKey, `{"productId":"MWBLU","amount":1,"cadence":"daily","startAt":"2099-01-01T00:00:00Z"}`))
+		location := rec.Header().Get("Location")
+		assert.Equal(t, "customer", decodeSubscription(t, rec).Owner)
+
+		for _, req := range []*http.Request{
+			newRequest("GET", location, otherCustomerKey),
+			newBodyRequest("PATCH", location, otherCustomerKey, `{"amount":50}`),
+			newRequest("POST", location+"/pause", otherCustomerKey),
+			newRequest("POST", location+"/resume", otherCustomerKey),
+			newRequest("GET", location+"/runs", otherCustomerKey),
+			newRequest("DELETE", location, otherCustomerKey),
+		} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("invalid cadence", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newBodyRequest("POST", "/v1/subscriptions", customerKey, `{"productId":"MWBLU","amount":2,"cadence":"yearly"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown subscription", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest("GET", "/v1/subscriptions/blablabla", customerKey))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func decodeSubscription(t *testing.T, rec *httptest.ResponseRecorder) models.Subscription {
+	var resp struct {
+		Data models.Subscription `json:"data"`
+	}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Data
+}
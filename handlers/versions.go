@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/ratelimit"
+)
+
+// legacySunset is the date after which the unversioned routes will be removed
+var legacySunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+// route describes a single endpoint of a versioned API
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// role is the minimum role allowed to call the route, empty for public routes
+	role auth.Role
+	// limit is the rate limit policy of the route, the default policy is used when empty
+	limit string
+	// queryToken also accepts a bearer token as the access_token query parameter of WebSocket upgrades
+	queryToken bool
+}
+
+// apiVersion groups the routes served under a common path prefix.
+// Every version is backed by the same repo, so a new version only needs
+// its own handlers for the payload shapes that differ from the previous one.
+type apiVersion struct {
+	prefix string
+	routes []route
+}
+
+// apiVersions lists all the API versions served by the app
+func apiVersions(handler Handler) []apiVersion {
+	return []apiVersion{
+		{prefix: "/v1", routes: v1Routes(handler)},
+	}
+}
+
+// v1Routes lists the routes of the first version of the API
+func v1Routes(handler Handler) []route {
+	return []route{
+		{method: "GET", path: "/products", handler: handler.ProductIndex, role: auth.Role_Customer},
+		{method: "GET", path: "/products/export", handler: handler.ProductExport, role: auth.Role_Customer},
+		{method: "POST", path: "/products/import", handler: handler.ProductImport, role: auth.Role_Admin},
+		{method: "GET", path: "/families", handler: handler.FamilyIndex, role: auth.Role_Customer},
+		{method: "GET", path: "/families/{familyId}", handler: handler.FamilyShow, role: auth.Role_Customer},
+		{method: "GET", path: "/orders", handler: handler.OrderIndex, role: auth.Role_Operator},
+		// the export is registered before /orders/{orderId} so it is not taken for an order id
+		{method: "GET", path: "/orders/export", handler: handler.OrderExport, role: auth.Role_Operator},
+		{method: "GET", path: "/orders/scheduled", handler: handler.OrderScheduledIndex, role: auth.Role_Operator},
+		{method: "GET", path: "/orders/{orderId}", handler: handler.OrderShow, role: auth.Role_Customer},
+		{method: "POST", path: "/orders", handler: handler.OrderInsert, role: auth.Role_Customer, limit: config.OrdersRateLimit},
+		{method: "POST", path: "/orders/batch", handler: handler.OrderBatchInsert, role: auth.Role_Customer},
+		{method: "POST", path: "/close", handler: handler.Close, role: auth.Role_Admin},
+		{method: "POST", path: "/open", handler: handler.Open, role: auth.Role_Admin},
+		{method: "GET", path: "/stats", handler: handler.Stats, role: auth.Role_Operator},
+		{method: "DELETE", path: "/orders/{orderId}", handler: handler.OrderReverse, role: auth.Role_Operator},
+		{method: "POST", path: "/orders/{orderId}/cancel", handler: handler.OrderCancel, role: auth.Role_Customer},
+		{method: "POST", path: "/orders/{orderId}/returns", handler: handler.OrderReturn, role: auth.Role_Operator},
+		{method: "GET", path: "/orders/{orderId}/returns", handler: handler.OrderReturns, role: auth.Role_Operator},
+		{method: "GET", path: "/metrics", handler: handler.Metrics, role: auth.Role_Operator},
+		{method: "GET", path: "/events", handler: handler.EventStream, role: auth.Role_Operator},
+		{method: "GET", path: "/live", handler: handler.LiveFeed, role: auth.Role_Customer, queryToken: true},
+		{method: "POST", path: "/webhooks", handler: handler.WebhookInsert, role: auth.Role_Admin},
+		{method: "GET", path: "/webhooks", handler: handler.WebhookIndex, role: auth.Role_Operator},
+		{method: "DELETE", path: "/webhooks/{webhookId}", handler: handler.WebhookDelete, role: auth.Role_Admin},
+		{method: "GET", path: "/webhooks/{webhookId}/deliveries", handler: handler.WebhookDeliveries, role: auth.Role_Operator},
+		{method: "GET", path: "/webhooks/dead-letters", handler: handler.DeadLetterIndex, role: auth.Role_Operator},
+		{method: "POST", path: "/webhooks/dead-letters/{deliveryId}/replay", handler: handler.DeadLetterReplay, role: auth.Role_Operator},
+		{method: "GET", path: "/alerts", handler: handler.AlertIndex, role: auth.Role_Operator},
+		{method: "POST", path: "/suppliers", handler: handler.SupplierInsert, role: auth.Role_Admin},
+		{method: "GET", path: "/suppliers", handler: handler.SupplierIndex, role: auth.Role_Operator},
+		{method: "POST", path: "/purchase-orders", handler: handler.PurchaseOrderInsert, role: auth.Role_Operator},
+		{method: "GET", path: "/purchase-orders", handler: handler.PurchaseOrderIndex, role: auth.Role_Operator},
+		{method: "GET", path: "/purchase-orders/{purchaseOrderId}", handler: handler.PurchaseOrderShow, role: auth.Role_Operator},
+		{method: "POST", path: "/purchase-orders/{purchaseOrderId}/send", handler: handler.PurchaseOrderSend, role: auth.Role_Operator},
+		{method: "POST", path: "/purchase-orders/{purchaseOrderId}/cancel", handler: handler.PurchaseOrderCancel, role: auth.Role_Operator},
+		{method: "POST", path: "/purchase-orders/{purchaseOrderId}/receipts", handler: handler.PurchaseOrderReceive, role: auth.Role_Operator},
+		{method: "GET", path: "/purchase-orders/{purchaseOrderId}/receipts", handler: handler.PurchaseOrderReceipts, role: auth.Role_Operator},
+		{method: "POST", path: "/reservations", handler: handler.ReservationInsert, role: auth.Role_Customer, limit: config.OrdersRateLimit},
+		{method: "GET", path: "/reservations", handler: handler.ReservationIndex, role: auth.Role_Operator},
+		{method: "GET", path: "/reservations/{reservationId}", handler: handler.ReservationShow, role: auth.Role_Customer},
+		{method: "POST", path: "/reservations/{reservationId}/confirm", handler: handler.ReservationConfirm, role: auth.Role_Customer, limit: config.OrdersRateLimit},
+		{method: "POST", path: "/reservations/{reservationId}/release", handler: handler.ReservationRelease, role: auth.Role_Customer},
+		{method: "POST", path: "/subscriptions", handler: handler.SubscriptionInsert, role: auth.Role_Customer, limit: config.OrdersRateLimit},
+		{method: "GET", path: "/subscriptions", handler: handler.SubscriptionIndex, role: auth.Role_Operator},
+		{method: "GET", path: "/subscriptions/{subscriptionId}", handler: handler.SubscriptionShow, role: auth.Role_Customer},
+		{method: "PATCH", path: "/subscriptions/{subscriptionId}", handler: handler.SubscriptionUpdate, role: auth.Role_Customer},
+		{method: "DELETE", path: "/subscriptions/{subscriptionId}", handler: handler.SubscriptionCancel, role: auth.Role_Customer},
+		{method: "POST", path: "/subscriptions/{subscriptionId}/pause", handler: handler.SubscriptionPause, role: auth.Role_Customer},
+		{method: "POST", path: "/subscriptions/{subscriptionId}/resume", handler: handler.SubscriptionResume, role: auth.Role_Customer},
+		{method: "GET", path: "/subscriptions/{subscriptionId}/runs", handler: handler.SubscriptionRuns, role: auth.Role_Customer},
+		{method: "GET", path: "/graphql", handler: handler.GraphQL, role: auth.Role_Customer},
+		{method: "POST", path: "/graphql", handler: handler.GraphQL, role: auth.Role_Customer},
+	}
+}
+
+// mountRoutes binds the given routes to the router
+func mountRoutes(router *mux.Router, routes []route, authenticator *auth.Authenticator, limiters map[string]*ratelimit.Limiter) {
+	for _, rt := range routes {
+		var h http.Handler = rt.handler
+		if rt.role != "" {
+			h = requireRole(rt.role, h)
+		}
+		h = rateLimit(limiters, rt.limit, h)
+		if rt.queryToken {
+			h = queryTokenAuth(authenticator, h)
+		}
+		router.Methods(rt.method).Path(rt.path).Handler(h)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/orders-app/models"
+	"github.com/orders-app/webhooks"
+)
+
+// webhookRequest is the body of a webhook subscription
+type webhookRequest struct {
+	URL    string             `json:"url"`
+	Events []models.EventType `json:"events"`
+	Secret string             `json:"secret"`
+}
+
+// WebhookInsert subscribes a new webhook to events
+func (h *handler) WebhookInsert(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, fmt.Errorf("invalid webhook body:%v", err))
+		return
+	}
+	webhook, err := h.webhooks.CreateWebhook(req.URL, req.Events, req.Secret)
+	if errors.Is(err, webhooks.ErrInvalidWebhook) {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, withoutSecret(webhook), nil)
+}
+
+// WebhookIndex displays all webhook subscriptions
+func (h *handler) WebhookIndex(w http.ResponseWriter, r *http.Request) {
+	all := h.webhooks.GetWebhooks()
+	for i := range all {
+		all[i] = withoutSecret(all[i])
+	}
+	writeResponse(w, http.StatusOK, all, nil)
+}
+
+// WebhookDelete removes a webhook subscription
+func (h *handler) WebhookDelete(w http.ResponseWriter, r *http.Request) {
+	webhookId := mux.Vars(r)["webhookId"]
+	if err := h.webhooks.DeleteWebhook(webhookId); err != nil {
+		writeResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, "The webhook was deleted", nil)
+}
+
+// WebhookDeliveries displays the deliveries of a webhook with their attempt history
+func (h *handler) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId := mux.Vars(r)["webhookId"]
+	deliveries, err := h.webhooks.GetDeliveries(webhookId)
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, deliveries, nil)
+}
+
+// DeadLetterIndex displays the deliveries which exhausted all their attempts
+func (h *handler) DeadLetterIndex(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, h.webhooks.GetDeadLetters(), nil)
+}
+
+// DeadLetterReplay schedules a dead delivery for another round of attempts
+func (h *handler) DeadLetterReplay(w http.ResponseWriter, r *http.Request) {
+	deliveryId := mux.Vars(r)["deliveryId"]
+	delivery, err := h.webhooks.Replay(deliveryId)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	writeResponse(w, http.StatusAccepted, delivery, nil)
+}
+
+func withoutSecret(webhook models.Webhook) models.Webhook {
+	webhook.Secret = ""
+	return webhook
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 )
 
@@ -29,3 +31,14 @@ func SyncLogger() {
 		_ = Log.Sync()
 	}
 }
+
+// LogRequest logs a request handled by any of the APIs
+func LogRequest(api, method, status string, duration time.Duration, subject string) {
+	Log.Info("Request handled",
+		zap.String("api", api),
+		zap.String("method", method),
+		zap.String("status", status),
+		zap.Duration("duration", duration),
+		zap.String("subject", subject),
+	)
+}
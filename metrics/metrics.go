@@ -0,0 +1,23 @@
+package metrics
+
+import "sync"
+
+// Source returns the current value of a metric
+type Source func() any
+
+var sources sync.Map
+
+// Register exposes a metric under the given name, replacing any previous source of that name
+func Register(name string, source Source) {
+	sources.Store(name, source)
+}
+
+// Snapshot collects the current value of all registered metrics
+func Snapshot() map[string]any {
+	snapshot := make(map[string]any)
+	sources.Range(func(key, value any) bool {
+		snapshot[key.(string)] = value.(Source)()
+		return true
+	})
+	return snapshot
+}
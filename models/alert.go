@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// AlertLevel is how critical the stock of a product is
+type AlertLevel string
+
+const (
+	// AlertLevel_LowStock is raised when the stock falls below the reorder threshold of the product
+	AlertLevel_LowStock AlertLevel = "low_stock"
+	// AlertLevel_OutOfStock is raised when the stock runs out
+	AlertLevel_OutOfStock AlertLevel = "out_of_stock"
+)
+
+// AlertLevels lists all alert levels
+var AlertLevels = []AlertLevel{AlertLevel_LowStock, AlertLevel_OutOfStock}
+
+// Breached checks whether the stock of the product is at the alert level
+func (l AlertLevel) Breached(p Product) bool {
+	switch l {
+	case AlertLevel_LowStock:
+		return p.ReorderThreshold > 0 && p.Stock < p.ReorderThreshold
+	case AlertLevel_OutOfStock:
+		return p.Stock <= 0
+	default:
+		return false
+	}
+}
+
+// Alert tracks a stock level of a product, it is open while the stock stays at the level
+type Alert struct {
+	// ID is the product ID and the level, a product has at most one alert per level
+	ID        string     `json:"id"`
+	ProductID string     `json:"productId"`
+	Level     AlertLevel `json:"level"`
+	Open      bool       `json:"open"`
+	// Stock and Threshold are the values of the product when the alert last changed
+	Stock     int `json:"stock"`
+	Threshold int `json:"threshold"`
+	// Occurrences counts how often the stock fell to the level, including the times no new alert was raised
+	Occurrences int    `json:"occurrences"`
+	RaisedAt    string `json:"raisedAt"`
+	ResolvedAt  string `json:"resolvedAt,omitempty"`
+}
+
+// NewAlert creates an open alert for the product at the given level
+func NewAlert(p Product, level AlertLevel) Alert {
+	return Alert{
+		ID:          AlertID(p.ID, level),
+		ProductID:   p.ID,
+		Level:       level,
+		Open:        true,
+		Stock:       p.Stock,
+		Threshold:   p.ReorderThreshold,
+		Occurrences: 1,
+		RaisedAt:    time.Now().Format(timeFormat),
+	}
+}
+
+// AlertID returns the ID of the alert of a product at a level
+func AlertID(productID string, level AlertLevel) string {
+	return productID + ":" + string(level)
+}
+
+// Resolve closes the alert once the stock of the product recovered from the level
+func (a *Alert) Resolve(p Product) {
+	a.Open = false
+	a.Stock = p.Stock
+	a.Threshold = p.ReorderThreshold
+	a.ResolvedAt = time.Now().Format(timeFormat)
+}
+
+// Reopen opens a resolved alert again without raising it anew, when the stock falls back to the level shortly after
+func (a *Alert) Reopen(p Product) {
+	a.Open = true
+	a.Stock = p.Stock
+	a.Threshold = p.ReorderThreshold
+	a.Occurrences++
+	a.ResolvedAt = ""
+}
@@ -0,0 +1,7 @@
+package models
+
+// BatchEntry is the outcome of one item of an order batch, either the accepted order or the reason it was not placed
+type BatchEntry struct {
+	Order *Order `json:"order,omitempty"`
+	Error string `json:"error,omitempty"`
+}
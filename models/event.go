@@ -0,0 +1,115 @@
+package models
+
+import "time"
+
+type EventType string
+
+const (
+	EventType_OrderCreated   EventType = "order.created"
+	EventType_OrderCompleted EventType = "order.completed"
+	EventType_OrderRejected  EventType = "order.rejected"
+	// EventType_OrderBackordered is emitted when an order waits for a restock
+	EventType_OrderBackordered EventType = "order.backordered"
+	// EventType_OrderCancelled is emitted when an order is cancelled before it was processed
+	EventType_OrderCancelled EventType = "order.cancelled"
+	// EventType_OrderScheduled is emitted when an order is scheduled to be placed at a later time
+	EventType_OrderScheduled         EventType = "order.scheduled"
+	EventType_OrderReversalRequested EventType = "order.reversal_requested"
+	// EventType_OrderReturned is emitted when part of a completed order is returned
+	EventType_OrderReturned EventType = "order.returned"
+	EventType_OrderReversed EventType = "order.reversed"
+	// EventType_OrderReversalFailed is emitted when a reversal request could not be queued
+	EventType_OrderReversalFailed EventType = "order.reversal_failed"
+	EventType_StockChanged        EventType = "stock.changed"
+	// EventType_ProductUpdated is emitted when a product is created or changed by an import
+	EventType_ProductUpdated EventType = "product.updated"
+	// EventType_ProductRemoved is emitted when a product is removed from the catalogue
+	EventType_ProductRemoved EventType = "product.removed"
+	// EventType_StockAlert is emitted when the stock of a product falls below its reorder threshold or runs out
+	EventType_StockAlert EventType = "stock.alert"
+)
+
+// EventTypes lists all known event types
+var EventTypes = []EventType{
+	EventType_OrderCreated,
+	EventType_OrderCompleted,
+	EventType_OrderRejected,
+	EventType_OrderBackordered,
+	EventType_OrderCancelled,
+	EventType_OrderScheduled,
+	EventType_OrderReversalRequested,
+	EventType_OrderReturned,
+	EventType_OrderReversed,
+	EventType_OrderReversalFailed,
+	EventType_StockChanged,
+	EventType_ProductUpdated,
+	EventType_ProductRemoved,
+	EventType_StockAlert,
+}
+
+// Event describes a change of an order or a product
+type Event struct {
+	// ID is the sequence number of the event, assigned when it is published
+	ID        uint64    `json:"id"`
+	Type      EventType `json:"type"`
+	OrderID   string    `json:"orderId,omitempty"`
+	ProductID string    `json:"productId,omitempty"`
+	Order     *Order    `json:"order,omitempty"`
+	Product   *Product  `json:"product,omitempty"`
+	Alert     *Alert    `json:"alert,omitempty"`
+	CreatedAt string    `json:"createdAt"`
+}
+
+// NewOrderEvent creates an event about the given order
+func NewOrderEvent(eventType EventType, order Order) Event {
+	return Event{
+		Type:      eventType,
+		OrderID:   order.ID,
+		ProductID: order.Item.ProductID,
+		Order:     &order,
+		CreatedAt: time.Now().Format(timeFormat),
+	}
+}
+
+// NewProductEvent creates an event about the given product
+func NewProductEvent(eventType EventType, product Product) Event {
+	return Event{
+		Type:      eventType,
+		ProductID: product.ID,
+		Product:   &product,
+		CreatedAt: time.Now().Format(timeFormat),
+	}
+}
+
+// NewAlertEvent creates an event about the given alert of a product
+func NewAlertEvent(alert Alert, product Product) Event {
+	return Event{
+		Type:      EventType_StockAlert,
+		ProductID: product.ID,
+		Product:   &product,
+		Alert:     &alert,
+		CreatedAt: time.Now().Format(timeFormat),
+	}
+}
+
+// OrderEventType returns the event type matching the status of a processed order
+func OrderEventType(order Order) EventType {
+	switch OrderStatus(order.Status) {
+	case OrderStatus_Completed:
+		return EventType_OrderCompleted
+	case OrderStatus_Reversed:
+		return EventType_OrderReversed
+	case OrderStatus_ReversalRequested:
+		return EventType_OrderReversalRequested
+	case OrderStatus_new:
+		return EventType_OrderCreated
+	case OrderStatus_Backordered:
+		return EventType_OrderBackordered
+	case OrderStatus_Cancelled:
+		return EventType_OrderCancelled
+	case OrderStatus_Scheduled:
+		return EventType_OrderScheduled
+	default:
+		return EventType_OrderRejected
+	}
+}
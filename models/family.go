@@ -0,0 +1,28 @@
+package models
+
+import "strings"
+
+// Family groups the variants of a product, e.g. the flavours of a mineral water
+type Family struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Variants []Product `json:"variants,omitempty"`
+}
+
+// FamilyID derives the ID of the family a product name belongs to, e.g. "mineral-water" for "Mineral Water"
+func FamilyID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
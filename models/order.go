@@ -14,24 +14,58 @@ const (
 	OrderStatus_Rejected          OrderStatus = "Rejected"
 	OrderStatus_ReversalRequested OrderStatus = "ReversalRequested"
 	OrderStatus_Reversed          OrderStatus = "Reversed"
+	// OrderStatus_Backordered orders wait for a restock of a product allowing backorders
+	OrderStatus_Backordered OrderStatus = "Backordered"
+	// OrderStatus_Cancelled orders were cancelled before they were processed
+	OrderStatus_Cancelled OrderStatus = "Cancelled"
+	// OrderStatus_Scheduled orders wait for the time they are placed at
+	OrderStatus_Scheduled OrderStatus = "Scheduled"
 )
 
 const timeFormat = "2006-01-02 15:04:05.000"
 
 type Order struct {
-	ID        string  `json:"id,omitempty"`
-	Item      Item    `json:"item"`
+	ID   string `json:"id,omitempty"`
+	Item Item   `json:"item"`
+	// FamilyID is the family of the ordered variant, set when the order is processed
+	FamilyID  string  `json:"familyId,omitempty"`
 	Total     float64 `json:"total,omitempty"`
 	Error     string  `json:"error,omitempty"`
 	CreatedAt string  `json:"createdAt,omitempty"`
 	Status    string  `json:"status,omitempty"`
+	// ReservationID is set for orders confirming a reservation, they take the reserved stock
+	ReservationID string `json:"reservationId,omitempty"`
+	// BackorderedAt is set for orders which waited for a restock
+	BackorderedAt string `json:"backorderedAt,omitempty"`
+	// ProcessAt is the time a scheduled order is placed at
+	ProcessAt string `json:"processAt,omitempty"`
+	// Returned is the amount returned so far, Refunded is the part of the total refunded for it
+	Returned int     `json:"returned,omitempty"`
+	Refunded float64 `json:"refunded,omitempty"`
+	// Owner is the subject of the caller who placed the order, empty for orders the app placed itself
+	Owner string `json:"owner,omitempty"`
 }
 
+// Item references the ordered variant by its SKU, the product ID
 type Item struct {
 	ProductID string `json:"productId"`
 	Amount    int    `json:"amount"`
 }
 
+// OrderFilter selects orders when listing them, empty fields match everything
+type OrderFilter struct {
+	Status    string
+	ProductID string
+}
+
+// Matches checks whether the order passes the filter
+func (f OrderFilter) Matches(o Order) bool {
+	if f.Status != "" && f.Status != o.Status {
+		return false
+	}
+	return f.ProductID == "" || f.ProductID == o.Item.ProductID
+}
+
 func NewOrder(item Item) Order {
 	return Order{
 		ID:        uuid.New().String(),
@@ -41,6 +75,32 @@ func NewOrder(item Item) Order {
 	}
 }
 
+// Schedule makes the order wait until it is placed at the given time, which is kept in local time
+// like all other times of the order
+func (o *Order) Schedule(processAt time.Time) {
+	o.Status = string(OrderStatus_Scheduled)
+	o.ProcessAt = processAt.Local().Format(timeFormat)
+}
+
+// ProcessTime is the time a scheduled order is placed at, the zero time for orders which are not scheduled
+func (o Order) ProcessTime() time.Time {
+	processAt, _ := time.ParseInLocation(timeFormat, o.ProcessAt, time.Local)
+	return processAt
+}
+
+// Backorder makes the order wait for a restock, it keeps the time it was first backordered
+func (o *Order) Backorder() {
+	o.Status = string(OrderStatus_Backordered)
+	if o.BackorderedAt == "" {
+		o.BackorderedAt = time.Now().Format(timeFormat)
+	}
+}
+
+// Returnable is the amount of the order which was not returned yet
+func (o Order) Returnable() int {
+	return o.Item.Amount - o.Returned
+}
+
 func (o *Order) Complete() {
 	if o.Status == string(OrderStatus_ReversalRequested) {
 		o.Status = string(OrderStatus_Reversed)
@@ -1,8 +1,128 @@
 package models
 
+import (
+	"slices"
+	"strings"
+)
+
+// Product is a variant of a product family which can be ordered, its ID is the SKU of the variant
 type Product struct {
-	ID    string  `json:"id,omitempty"`
-	Name  string  `json:"name,omitempty"`
-	Price float64 `json:"price,omitempty"`
-	Stock int     `json:"stock,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	FamilyID string   `json:"familyId,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Variety  string   `json:"variety,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Price    float64  `json:"price,omitempty"`
+	// Stock is the stock available to order, it excludes the reserved stock
+	Stock int `json:"stock,omitempty"`
+	// Reserved is the stock held by reservations until they are confirmed, released or expire
+	Reserved int `json:"reserved,omitempty"`
+	// ReorderThreshold raises a low stock alert when the stock falls below it, zero disables the alert
+	ReorderThreshold int `json:"reorderThreshold,omitempty"`
+	// AllowBackorder makes orders wait for a restock rather than rejecting them when the stock is not enough
+	AllowBackorder bool `json:"allowBackorder,omitempty"`
+}
+
+// Equal checks whether two products hold the same values
+func (p Product) Equal(other Product) bool {
+	return p.ID == other.ID &&
+		p.FamilyID == other.FamilyID &&
+		p.Name == other.Name &&
+		p.Variety == other.Variety &&
+		p.Category == other.Category &&
+		slices.Equal(p.Tags, other.Tags) &&
+		p.Price == other.Price &&
+		p.Stock == other.Stock &&
+		p.Reserved == other.Reserved &&
+		p.ReorderThreshold == other.ReorderThreshold &&
+		p.AllowBackorder == other.AllowBackorder
+}
+
+// HasTag checks whether the product is tagged with the given tag
+func (p Product) HasTag(tag string) bool {
+	return slices.Contains(p.Tags, tag)
+}
+
+// ProductQuery selects, orders and pages products when searching them, empty fields match everything
+type ProductQuery struct {
+	// Text matches products whose name or variety contain words starting with each of the query words
+	Text     string
+	Category string
+	Tag      string
+	// InStock only matches products with stock left
+	InStock  bool
+	MinPrice float64
+	// MaxPrice is ignored when zero
+	MaxPrice float64
+	// Sort is one of name, price or stock, prefixed by - to sort descending, products are sorted by ID by default
+	Sort string
+	// Page starts at 1
+	Page     int
+	PageSize int
+}
+
+// Matches checks whether the product passes the category, tag, stock and price filters of the query
+func (q ProductQuery) Matches(p Product) bool {
+	if q.Category != "" && !strings.EqualFold(q.Category, p.Category) {
+		return false
+	}
+	if q.Tag != "" && !slices.ContainsFunc(p.Tags, func(tag string) bool { return strings.EqualFold(tag, q.Tag) }) {
+		return false
+	}
+	if q.InStock && p.Stock <= 0 {
+		return false
+	}
+	if p.Price < q.MinPrice {
+		return false
+	}
+	return q.MaxPrice == 0 || p.Price <= q.MaxPrice
+}
+
+// ProductPage is one page of the products matching a query
+type ProductPage struct {
+	Products []Product
+	// Total is the number of products matching the query across all pages
+	Total int
+}
+
+// ImportMode selects how imported products are applied to the catalogue
+type ImportMode string
+
+const (
+	// ImportMode_Upsert creates new products and updates existing ones
+	ImportMode_Upsert ImportMode = "upsert"
+	// ImportMode_Replace makes the import the whole catalogue, removing the products it does not list
+	ImportMode_Replace ImportMode = "replace"
+	// ImportMode_DryRun validates the import and reports the changes without applying them
+	ImportMode_DryRun ImportMode = "dry-run"
+)
+
+// ImportError describes why a line of a product import was rejected
+type ImportError struct {
+	// Line is the line of a CSV upload, or the position in the array of a JSON upload, starting at 1
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport summarises a product import
+type ImportReport struct {
+	Mode    ImportMode    `json:"mode"`
+	Created []string      `json:"created"`
+	Updated []string      `json:"updated"`
+	Removed []string      `json:"removed"`
+	Errors  []ImportError `json:"errors,omitempty"`
+	// Kept lists the products missing from a replacement which were kept as they hold reserved stock or waiting backorders
+	Kept []string `json:"kept,omitempty"`
+}
+
+// CatalogueChange is a change of the catalogue file to apply to a product
+type CatalogueChange struct {
+	// Product holds the product as listed in the file
+	Product Product
+	// Created is set for products which were not listed in the file before
+	Created bool
+	// StockDelta is how much the stock listed in the file changed, it is added to the current stock
+	StockDelta int
 }
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supplier delivers products to restock them
+type Supplier struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	// ProductIDs lists the products the supplier delivers
+	ProductIDs []string `json:"productIds"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// NewSupplier creates a supplier of the given products
+func NewSupplier(name, email string, productIDs []string) Supplier {
+	return Supplier{
+		ID:         uuid.New().String(),
+		Name:       name,
+		Email:      email,
+		ProductIDs: productIDs,
+		CreatedAt:  time.Now().Format(timeFormat),
+	}
+}
+
+// Supplies checks whether the supplier delivers the product
+func (s Supplier) Supplies(productID string) bool {
+	return slices.Contains(s.ProductIDs, productID)
+}
+
+// PurchaseOrderStatus is the state of a purchase order in the replenishment workflow
+type PurchaseOrderStatus string
+
+const (
+	// PurchaseOrderStatus_Draft purchase orders can still be changed before they are sent
+	PurchaseOrderStatus_Draft PurchaseOrderStatus = "draft"
+	// PurchaseOrderStatus_Sent purchase orders were sent to the supplier and wait for the goods
+	PurchaseOrderStatus_Sent PurchaseOrderStatus = "sent"
+	// PurchaseOrderStatus_PartiallyReceived purchase orders received some of the goods
+	PurchaseOrderStatus_PartiallyReceived PurchaseOrderStatus = "partially_received"
+	// PurchaseOrderStatus_Received purchase orders received all the goods
+	PurchaseOrderStatus_Received PurchaseOrderStatus = "received"
+	// PurchaseOrderStatus_Cancelled purchase orders were cancelled before receiving any goods
+	PurchaseOrderStatus_Cancelled PurchaseOrderStatus = "cancelled"
+)
+
+// PurchaseLine is the quantity of a product ordered from a supplier and how much of it was received
+type PurchaseLine struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+	Received  int    `json:"received"`
+}
+
+// PurchaseOrder orders products from a supplier to restock them
+type PurchaseOrder struct {
+	ID         string              `json:"id"`
+	SupplierID string              `json:"supplierId"`
+	Lines      []PurchaseLine      `json:"lines"`
+	Status     PurchaseOrderStatus `json:"status"`
+	// AutoGenerated is set for drafts created when a product fell below its reorder threshold
+	AutoGenerated bool   `json:"autoGenerated,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+// NewPurchaseOrder creates a draft purchase order of the lines from the supplier
+func NewPurchaseOrder(supplierID string, lines []PurchaseLine) PurchaseOrder {
+	now := time.Now().Format(timeFormat)
+	return PurchaseOrder{
+		ID:         uuid.New().String(),
+		SupplierID: supplierID,
+		Lines:      lines,
+		Status:     PurchaseOrderStatus_Draft,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Equal checks whether two purchase orders hold the same values
+func (po PurchaseOrder) Equal(other PurchaseOrder) bool {
+	return po.ID == other.ID &&
+		po.SupplierID == other.SupplierID &&
+		slices.Equal(po.Lines, other.Lines) &&
+		po.Status == other.Status &&
+		po.AutoGenerated == other.AutoGenerated &&
+		po.CreatedAt == other.CreatedAt &&
+		po.UpdatedAt == other.UpdatedAt
+}
+
+// IsOpen checks whether the purchase order still expects goods
+func (po PurchaseOrder) IsOpen() bool {
+	switch po.Status {
+	case PurchaseOrderStatus_Draft, PurchaseOrderStatus_Sent, PurchaseOrderStatus_PartiallyReceived:
+		return true
+	default:
+		return false
+	}
+}
+
+// Includes checks whether the purchase order has a line of the product
+func (po PurchaseOrder) Includes(productID string) bool {
+	return slices.ContainsFunc(po.Lines, func(l PurchaseLine) bool { return l.ProductID == productID })
+}
+
+// CanTransition checks whether the purchase order can be sent or cancelled in its current status
+func (po PurchaseOrder) CanTransition(to PurchaseOrderStatus) bool {
+	switch to {
+	case PurchaseOrderStatus_Sent:
+		return po.Status == PurchaseOrderStatus_Draft
+	case PurchaseOrderStatus_Cancelled:
+		return po.Status == PurchaseOrderStatus_Draft || po.Status == PurchaseOrderStatus_Sent
+	default:
+		return false
+	}
+}
+
+// Touch records that the purchase order changed
+func (po *PurchaseOrder) Touch() {
+	po.UpdatedAt = time.Now().Format(timeFormat)
+}
+
+// StockReceipt is the audit entry of goods received for a purchase order
+type StockReceipt struct {
+	ID              string `json:"id"`
+	PurchaseOrderID string `json:"purchaseOrderId"`
+	ProductID       string `json:"productId"`
+	Quantity        int    `json:"quantity"`
+	StockBefore     int    `json:"stockBefore"`
+	StockAfter      int    `json:"stockAfter"`
+	// ReceivedBy is the subject of the caller who received the goods
+	ReceivedBy string `json:"receivedBy"`
+	ReceivedAt string `json:"receivedAt"`
+}
+
+// NewStockReceipt creates the audit entry of a product restocked from before to after
+func NewStockReceipt(purchaseOrderID string, before, after Product, receivedBy string) StockReceipt {
+	return StockReceipt{
+		ID:              uuid.New().String(),
+		PurchaseOrderID: purchaseOrderID,
+		ProductID:       after.ID,
+		Quantity:        after.Stock - before.Stock,
+		StockBefore:     before.Stock,
+		StockAfter:      after.Stock,
+		ReceivedBy:      receivedBy,
+		ReceivedAt:      time.Now().Format(timeFormat),
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservationStatus is the state of a stock reservation
+type ReservationStatus string
+
+const (
+	// ReservationStatus_Active reservations hold stock until they expire
+	ReservationStatus_Active ReservationStatus = "active"
+	// ReservationStatus_Confirmed reservations were turned into an order taking the reserved stock
+	ReservationStatus_Confirmed ReservationStatus = "confirmed"
+	// ReservationStatus_Released reservations returned their stock on request
+	ReservationStatus_Released ReservationStatus = "released"
+	// ReservationStatus_Expired reservations returned their stock when they were not confirmed in time
+	ReservationStatus_Expired ReservationStatus = "expired"
+)
+
+// Reservation holds stock of a product for a checkout until it is confirmed as an order or released
+type Reservation struct {
+	ID        string            `json:"id"`
+	Item      Item              `json:"item"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt string            `json:"expiresAt"`
+	// OrderID is the order the reservation was confirmed as
+	OrderID   string `json:"orderId,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	// Owner is the subject of the caller who made the reservation, its order belongs to the same caller
+	Owner string `json:"owner,omitempty"`
+}
+
+// NewReservation creates an active reservation of the item expiring after the ttl
+func NewReservation(item Item, ttl time.Duration) Reservation {
+	now := time.Now()
+	return Reservation{
+		ID:        uuid.New().String(),
+		Item:      item,
+		Status:    ReservationStatus_Active,
+		ExpiresAt: now.Add(ttl).Format(timeFormat),
+		CreatedAt: now.Format(timeFormat),
+		UpdatedAt: now.Format(timeFormat),
+	}
+}
+
+// Expired checks whether the reservation is past its expiry at the given time
+func (r Reservation) Expired(now time.Time) bool {
+	expiresAt, err := time.ParseInLocation(timeFormat, r.ExpiresAt, time.Local)
+	return err != nil || !now.Before(expiresAt)
+}
+
+// Touch records that the reservation changed
+func (r *Reservation) Touch() {
+	r.UpdatedAt = time.Now().Format(timeFormat)
+}
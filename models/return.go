@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReturnLine is the quantity of an ordered product to return, an empty product ID refers to the product of the order
+type ReturnLine struct {
+	ProductID string `json:"productId,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// Return records goods of a completed order which were returned and restocked
+type Return struct {
+	ID        string `json:"id"`
+	OrderID   string `json:"orderId"`
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+	// Refund is the part of the order total refunded for the quantity
+	Refund    float64 `json:"refund"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// NewReturn creates the return of the quantity of an order
+func NewReturn(order Order, quantity int, refund float64) Return {
+	return Return{
+		ID:        uuid.New().String(),
+		OrderID:   order.ID,
+		ProductID: order.Item.ProductID,
+		Quantity:  quantity,
+		Refund:    refund,
+		CreatedAt: time.Now().Format(timeFormat),
+	}
+}
@@ -2,19 +2,56 @@ package models
 
 import "math"
 
-type Statistics struct {
-	CompletedOrders int     `json:"completedOrders"`
-	RejectedOrders  int     `json:"rejectedOrders"`
-	ReversedOrders  int     `json:"reversedOrders"`
+// Totals counts processed orders and the revenue they made
+type Totals struct {
+	CompletedOrders int `json:"completedOrders"`
+	RejectedOrders  int `json:"rejectedOrders"`
+	ReversedOrders  int `json:"reversedOrders"`
+	// BackorderedOrders counts the orders waiting for a restock
+	BackorderedOrders int `json:"backorderedOrders"`
+	// CancelledOrders counts the orders cancelled before they were processed
+	CancelledOrders int     `json:"cancelledOrders"`
 	Revenue         float64 `json:"revenue"`
 }
 
+// Statistics holds the totals of all orders, rolled up by product family and by variant
+type Statistics struct {
+	Totals
+	ByFamily  map[string]Totals `json:"byFamily,omitempty"`
+	ByVariant map[string]Totals `json:"byVariant,omitempty"`
+}
+
 // Combine adds the numbers from a two statistics objects
 func Combine(this, that Statistics) Statistics {
 	return Statistics{
-		CompletedOrders: this.CompletedOrders + that.CompletedOrders,
-		RejectedOrders:  this.RejectedOrders + that.RejectedOrders,
-		Revenue:         math.Round((this.Revenue+that.Revenue)*100) / 100,
-		ReversedOrders:  this.ReversedOrders + that.ReversedOrders,
+		Totals:    addTotals(this.Totals, that.Totals),
+		ByFamily:  combineTotals(this.ByFamily, that.ByFamily),
+		ByVariant: combineTotals(this.ByVariant, that.ByVariant),
+	}
+}
+
+func addTotals(this, that Totals) Totals {
+	return Totals{
+		CompletedOrders:   this.CompletedOrders + that.CompletedOrders,
+		RejectedOrders:    this.RejectedOrders + that.RejectedOrders,
+		Revenue:           math.Round((this.Revenue+that.Revenue)*100) / 100,
+		ReversedOrders:    this.ReversedOrders + that.ReversedOrders,
+		BackorderedOrders: this.BackorderedOrders + that.BackorderedOrders,
+		CancelledOrders:   this.CancelledOrders + that.CancelledOrders,
+	}
+}
+
+// combineTotals adds the totals of two roll-ups into a new map, so published statistics are never modified
+func combineTotals(this, that map[string]Totals) map[string]Totals {
+	if len(this) == 0 && len(that) == 0 {
+		return nil
+	}
+	combined := make(map[string]Totals, len(this)+len(that))
+	for key, totals := range this {
+		combined[key] = totals
+	}
+	for key, totals := range that {
+		combined[key] = addTotals(combined[key], totals)
 	}
+	return combined
 }
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionStatus is the state of a subscription
+type SubscriptionStatus string
+
+const (
+	// SubscriptionStatus_Active subscriptions place an order every cycle
+	SubscriptionStatus_Active SubscriptionStatus = "active"
+	// SubscriptionStatus_Paused subscriptions skip their cycles until they are resumed
+	SubscriptionStatus_Paused SubscriptionStatus = "paused"
+	// SubscriptionStatus_Cancelled subscriptions no longer place orders
+	SubscriptionStatus_Cancelled SubscriptionStatus = "cancelled"
+)
+
+// Cadence is how often a subscription places an order
+type Cadence string
+
+const (
+	Cadence_Daily   Cadence = "daily"
+	Cadence_Weekly  Cadence = "weekly"
+	Cadence_Monthly Cadence = "monthly"
+)
+
+// Valid checks whether the cadence is one of the known cadences
+func (c Cadence) Valid() bool {
+	return c == Cadence_Daily || c == Cadence_Weekly || c == Cadence_Monthly
+}
+
+// Cycle returns the time of the given cycle of a cadence starting at the start time, the start is cycle zero.
+// Every cycle is counted from the start so the runs do not drift, monthly cycles fall on the last day
+// of the months which are shorter than the start day.
+func (c Cadence) Cycle(start time.Time, cycle int) time.Time {
+	switch c {
+	case Cadence_Daily:
+		return start.AddDate(0, 0, cycle)
+	case Cadence_Weekly:
+		return start.AddDate(0, 0, 7*cycle)
+	default:
+		t := start.AddDate(0, cycle, 0)
+		if t.Day() != start.Day() {
+			// the day overflowed into the following month
+			t = t.AddDate(0, 0, -t.Day())
+		}
+		return t
+	}
+}
+
+// Subscription places an order of the same item at a regular cadence
+type Subscription struct {
+	ID      string             `json:"id"`
+	Item    Item               `json:"item"`
+	Cadence Cadence            `json:"cadence"`
+	Status  SubscriptionStatus `json:"status"`
+	// StartAt is when the first order is placed, the following cycles are counted from it
+	StartAt string `json:"startAt"`
+	// NextRunAt is when the next order is placed
+	NextRunAt string `json:"nextRunAt"`
+	// Cycles counts the cycles which were started, Failures those whose order was not placed or was rejected
+	Cycles    int    `json:"cycles"`
+	Failures  int    `json:"failures"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	// Owner is the subject of the caller who created the subscription, its orders belong to the same caller
+	Owner string `json:"owner,omitempty"`
+}
+
+// NewSubscription creates an active subscription of the item placing its first order at the given time,
+// which is kept in local time like all other times of the subscription
+func NewSubscription(item Item, cadence Cadence, startAt time.Time) Subscription {
+	now := time.Now().Format(timeFormat)
+	start := startAt.Local().Format(timeFormat)
+	return Subscription{
+		ID:        uuid.New().String(),
+		Item:      item,
+		Cadence:   cadence,
+		Status:    SubscriptionStatus_Active,
+		StartAt:   start,
+		NextRunAt: start,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Due checks whether an active subscription places its next order at the given time
+func (s Subscription) Due(now time.Time) (bool, error) {
+	if s.Status != SubscriptionStatus_Active {
+		return false, nil
+	}
+	nextRun, err := time.ParseInLocation(timeFormat, s.NextRunAt, time.Local)
+	if err != nil {
+		return false, fmt.Errorf("invalid next run of subscription %s: %v", s.ID, err)
+	}
+	return !now.Before(nextRun), nil
+}
+
+// Skip moves the next run to the first cycle after the given time, skipping the cycles which were missed
+func (s *Subscription) Skip(now time.Time) {
+	start, err := time.ParseInLocation(timeFormat, s.StartAt, time.Local)
+	if err != nil {
+		start = now
+	}
+	cycle := 0
+	for !s.Cadence.Cycle(start, cycle).After(now) {
+		cycle++
+	}
+	s.NextRunAt = s.Cadence.Cycle(start, cycle).Format(timeFormat)
+}
+
+// CanTransition checks whether the subscription may move to the given status
+func (s Subscription) CanTransition(to SubscriptionStatus) bool {
+	switch to {
+	case SubscriptionStatus_Paused:
+		return s.Status == SubscriptionStatus_Active
+	case SubscriptionStatus_Active:
+		return s.Status == SubscriptionStatus_Paused
+	case SubscriptionStatus_Cancelled:
+		return s.Status != SubscriptionStatus_Cancelled
+	}
+	return false
+}
+
+// Touch records that the subscription changed
+func (s *Subscription) Touch() {
+	s.UpdatedAt = time.Now().Format(timeFormat)
+}
+
+// SubscriptionRun records the order placed by a cycle of a subscription
+type SubscriptionRun struct {
+	SubscriptionID string `json:"subscriptionId"`
+	Cycle          int    `json:"cycle"`
+	OrderID        string `json:"orderId,omitempty"`
+	// OrderStatus is the status of the order once it was processed
+	OrderStatus string `json:"orderStatus,omitempty"`
+	// Error is why the order was not placed or was rejected, e.g. as the product was out of stock
+	Error string `json:"error,omitempty"`
+	RunAt string `json:"runAt"`
+}
+
+// NewSubscriptionRun creates the run of the current cycle of a subscription
+func NewSubscriptionRun(s Subscription) SubscriptionRun {
+	return SubscriptionRun{
+		SubscriptionID: s.ID,
+		Cycle:          s.Cycles,
+		RunAt:          time.Now().Format(timeFormat),
+	}
+}
+
+// Failed checks whether the run did not place an order or its order was rejected
+func (r SubscriptionRun) Failed() bool {
+	return r.Error != ""
+}
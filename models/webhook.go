@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatus_Pending   DeliveryStatus = "Pending"
+	DeliveryStatus_Succeeded DeliveryStatus = "Succeeded"
+	DeliveryStatus_Dead      DeliveryStatus = "Dead"
+)
+
+// Webhook is a subscription of a downstream system to events
+type Webhook struct {
+	ID     string      `json:"id,omitempty"`
+	URL    string      `json:"url"`
+	Events []EventType `json:"events"`
+	// Secret signs the deliveries, it is never returned by the API
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// Delivery is an event to be delivered to a webhook together with its attempts
+type Delivery struct {
+	ID        string            `json:"id"`
+	WebhookID string            `json:"webhookId"`
+	Event     Event             `json:"event"`
+	Status    string            `json:"status"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	CreatedAt string            `json:"createdAt"`
+	// Retries counts the failed attempts since the delivery was created or replayed
+	Retries       int       `json:"-"`
+	NextAttemptAt time.Time `json:"-"`
+}
+
+// DeliveryAttempt is the outcome of a single delivery attempt
+type DeliveryAttempt struct {
+	At         string `json:"at"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+func NewWebhook(url string, events []EventType, secret string) Webhook {
+	return Webhook{
+		ID:        uuid.New().String(),
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().Format(timeFormat),
+	}
+}
+
+// NewDelivery creates a pending delivery, its id is derived from the webhook and the event
+// so the same event is never delivered twice to a webhook
+func NewDelivery(webhookID string, event Event) Delivery {
+	return Delivery{
+		ID:            fmt.Sprintf("%s-%d", webhookID, event.ID),
+		WebhookID:     webhookID,
+		Event:         event,
+		Status:        string(DeliveryStatus_Pending),
+		CreatedAt:     time.Now().Format(timeFormat),
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// Subscribes checks whether the webhook wants to receive the given event type
+func (w Webhook) Subscribes(eventType EventType) bool {
+	for _, t := range w.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordAttempt appends the outcome of an attempt which started at the given time
+func (d *Delivery) RecordAttempt(start time.Time, statusCode int, err error) {
+	attempt := DeliveryAttempt{
+		At:         start.Format(timeFormat),
+		StatusCode: statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	d.Attempts = append(d.Attempts, attempt)
+}
@@ -0,0 +1,46 @@
+package purchasing
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// Replenisher is an event sink drafting a purchase order when a stock alert is raised for a product,
+// the drafts wait for an operator to review and send them
+type Replenisher struct {
+	repo repo.Repo
+	// lastID is the id of the last event received, events are delivered at least once
+	lastID uint64
+	lock   sync.Mutex
+}
+
+// NewReplenisher creates a replenisher drafting the purchase orders in the repo
+func NewReplenisher(r repo.Repo) *Replenisher {
+	return &Replenisher{repo: r}
+}
+
+func (p *Replenisher) Name() string {
+	return "replenisher"
+}
+
+// Publish drafts a purchase order for the product of a stock alert
+func (p *Replenisher) Publish(e models.Event) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if e.ID <= p.lastID {
+		return nil
+	}
+	p.lastID = e.ID
+	if e.Type != models.EventType_StockAlert {
+		return nil
+	}
+	// a failed draft is logged rather than retried, the next alert of the product drafts again
+	if _, err := p.repo.DraftReplenishment(e.ProductID); err != nil {
+		logger.Log.Warn(fmt.Sprintf("Drafting a purchase order for product %s failed: %v", e.ProductID, err))
+	}
+	return nil
+}
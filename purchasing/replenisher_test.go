@@ -0,0 +1,42 @@
+package purchasing
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	logger.InitLogger("test")
+	os.Exit(m.Run())
+}
+
+func Test_Replenisher(t *testing.T) {
+	rp, err := repo.New(config.Queue{Capacity: 10, EnqueueTimeout: time.Second, MaxBatchSize: 5}, "./input/products.csv", db.NewOutbox())
+	assert.Nil(t, err)
+	_, err = rp.CreateSupplier("Springs Ltd", "", []string{"MWBLU"})
+	assert.Nil(t, err)
+	p := NewReplenisher(rp)
+
+	changed := models.Event{ID: 1, Type: models.EventType_StockChanged, ProductID: "MWBLU"}
+	assert.Nil(t, p.Publish(changed))
+	assert.Empty(t, rp.GetPurchaseOrders(models.PurchaseOrderStatus_Draft))
+
+	alert := models.Event{ID: 2, Type: models.EventType_StockAlert, ProductID: "MWBLU"}
+	assert.Nil(t, p.Publish(alert))
+	// a redelivered alert does not draft twice
+	assert.Nil(t, p.Publish(alert))
+	drafts := rp.GetPurchaseOrders(models.PurchaseOrderStatus_Draft)
+	assert.Len(t, drafts, 1)
+	assert.True(t, drafts[0].AutoGenerated)
+}
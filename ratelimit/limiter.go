@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// idleTimeout is how long a full bucket is kept around before it is dropped
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is a token bucket rate limiter holding one bucket per key
+type Limiter struct {
+	rate      float64
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	allowed   uint64
+	rejected  uint64
+	lock      sync.Mutex
+	now       func() time.Time
+}
+
+// Result describes the state of a bucket after a request was taken from it
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+// Stats is a snapshot of the limiter state
+type Stats struct {
+	Rate     float64 `json:"rate"`
+	Burst    int     `json:"burst"`
+	Keys     int     `json:"keys"`
+	Allowed  uint64  `json:"allowed"`
+	Rejected uint64  `json:"rejected"`
+}
+
+// New creates a limiter refilling rate tokens per second up to burst tokens
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the given key if one is available
+func (l *Limiter) Allow(key string) Result {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from the bucket of the given key if that many are available
+func (l *Limiter) AllowN(key string, n int) Result {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	res := Result{Limit: l.burst}
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		res.Allowed = true
+		l.allowed++
+	} else {
+		res.RetryAfter = l.durationFor(float64(n) - b.tokens)
+		l.rejected++
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.durationFor(float64(l.burst) - b.tokens)
+	return res
+}
+
+// Charge takes cost tokens of the named policy from the bucket of the caller of a request
+type Charge func(policy string, cost int) Result
+
+type chargeKey struct{}
+
+// WithCharge returns a copy of the context carrying the charge of the caller
+func WithCharge(ctx context.Context, charge Charge) context.Context {
+	return context.WithValue(ctx, chargeKey{}, charge)
+}
+
+// ChargeFromContext returns the charge stored in the context if any
+func ChargeFromContext(ctx context.Context) (Charge, bool) {
+	charge, ok := ctx.Value(chargeKey{}).(Charge)
+	return charge, ok
+}
+
+// Stats returns a snapshot of the limiter state
+func (l *Limiter) Stats() Stats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return Stats{
+		Rate:     l.rate,
+		Burst:    l.burst,
+		Keys:     len(l.buckets),
+		Allowed:  l.allowed,
+		Rejected: l.rejected,
+	}
+}
+
+// sweep drops idle buckets so the map does not grow with every client ever seen
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// durationFor returns the time needed to refill the given amount of tokens
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Allow(t *testing.T) {
+	t.Run("burst then reject", func(t *testing.T) {
+		l, _ := newTestLimiter(1, 3)
+		for i := 0; i < 3; i++ {
+			assert.True(t, l.Allow("client").Allowed)
+		}
+		res := l.Allow("client")
+		assert.False(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.Equal(t, 3*time.Second, res.Reset)
+	})
+
+	t.Run("refill over time", func(t *testing.T) {
+		l, clock := newTestLimiter(2, 2)
+		assert.True(t, l.Allow("client").Allowed)
+		assert.True(t, l.Allow("client").Allowed)
+		assert.False(t, l.Allow("client").Allowed)
+
+		*clock = clock.Add(500 * time.Millisecond)
+		res := l.Allow("client")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+	})
+
+	t.Run("several tokens at once", func(t *testing.T) {
+		l, _ := newTestLimiter(1, 5)
+		assert.True(t, l.AllowN("client", 3).Allowed)
+		res := l.AllowN("client", 3)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, 2, res.Remaining)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.True(t, l.AllowN("client", 2).Allowed)
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		l, _ := newTestLimiter(1, 1)
+		assert.True(t, l.Allow("one").Allowed)
+		assert.False(t, l.Allow("one").Allowed)
+		assert.True(t, l.Allow("two").Allowed)
+
+		stats := l.Stats()
+		assert.Equal(t, 2, stats.Keys)
+		assert.Equal(t, uint64(2), stats.Allowed)
+		assert.Equal(t, uint64(1), stats.Rejected)
+	})
+
+	t.Run("idle buckets are dropped", func(t *testing.T) {
+		l, clock := newTestLimiter(1, 1)
+		l.Allow("one")
+		*clock = clock.Add(idleTimeout)
+		l.Allow("two")
+		assert.Equal(t, 1, l.Stats().Keys)
+	})
+}
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *time.Time) {
+	clock := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := New(rate, burst)
+	l.now = func() time.Time { return clock }
+	l.lastSweep = clock
+	return l, &clock
+}
@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/orders-app/logger"
+)
+
+// FulfillBackorders places the backorders of a product which fit into its stock back on the intake queue,
+// in the order they were backordered. Orders which can not be placed keep waiting for the next restock.
+func (r *repo) FulfillBackorders(productID string) {
+	product, err := r.products.Find(productID)
+	if err != nil {
+		return
+	}
+	for _, id := range r.backorders.Dispatch(productID, product.Stock) {
+		order, err := r.orders.Find(id)
+		if err == nil {
+			err = r.enqueue(context.Background(), order)
+		}
+		if err != nil {
+			logger.Log.Warn(fmt.Sprintf("Fulfilling backorder %s failed: %v", id, err))
+			r.backorders.Done(productID, id, true)
+		}
+	}
+}
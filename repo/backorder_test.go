@@ -0,0 +1,93 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// backorderedProduct has 30 in stock and allows backorders
+const backorderedProduct = "MWMAN"
+
+func Test_Backorders(t *testing.T) {
+	rp := initRepo(t)
+	first, err := rp.CreateOrder(context.Background(), models.Item{ProductID: backorderedProduct, Amount: 40})
+	assert.Nil(t, err)
+	assertStatus(t, rp, first.ID, models.OrderStatus_Backordered)
+
+	// stock is available, but the order waits behind the first one
+	second, err := rp.CreateOrder(context.Background(), models.Item{ProductID: backorderedProduct, Amount: 1})
+	assert.Nil(t, err)
+	assertStatus(t, rp, second.ID, models.OrderStatus_Backordered)
+
+	t.Run("products without backorders reject", func(t *testing.T) {
+		order, err := rp.CreateOrder(context.Background(), models.Item{ProductID: existingProduct, Amount: 500})
+		assert.Nil(t, err)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Rejected)
+	})
+
+	t.Run("a partial restock keeps the order", func(t *testing.T) {
+		restock(t, rp, 5)
+		rp.FulfillBackorders(backorderedProduct)
+		time.Sleep(50 * time.Millisecond)
+		assertStatus(t, rp, first.ID, models.OrderStatus_Backordered)
+		assertStatus(t, rp, second.ID, models.OrderStatus_Backordered)
+	})
+
+	t.Run("restock fulfills in order", func(t *testing.T) {
+		restock(t, rp, 10)
+		rp.FulfillBackorders(backorderedProduct)
+		fulfilled := assertStatus(t, rp, first.ID, models.OrderStatus_Completed)
+		assert.NotEmpty(t, fulfilled.BackorderedAt)
+		assertStatus(t, rp, second.ID, models.OrderStatus_Completed)
+		product, _ := rp.GetProduct(backorderedProduct)
+		assert.Equal(t, 4, product.Stock)
+	})
+
+	t.Run("cancel a backorder", func(t *testing.T) {
+		order, err := rp.CreateOrder(context.Background(), models.Item{ProductID: backorderedProduct, Amount: 100})
+		assert.Nil(t, err)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Backordered)
+		_, err = rp.CancelOrder(order.ID)
+		assert.Nil(t, err)
+		restock(t, rp, 100)
+		rp.FulfillBackorders(backorderedProduct)
+		time.Sleep(50 * time.Millisecond)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Cancelled)
+		product, _ := rp.GetProduct(backorderedProduct)
+		assert.Equal(t, 104, product.Stock)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			stats, err := rp.GetOrderStats(context.Background())
+			return err == nil && stats.CompletedOrders == 2 && stats.BackorderedOrders == 0 && stats.RejectedOrders == 1 &&
+				stats.CancelledOrders == 1
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+}
+
+// assertStatus waits for the order to reach the status
+func assertStatus(t *testing.T, rp interface {
+	GetOrder(id string) (models.Order, error)
+}, id string, status models.OrderStatus) models.Order {
+	var order models.Order
+	assert.Eventually(t, func() bool {
+		order, _ = rp.GetOrder(id)
+		return order.Status == string(status)
+	}, time.Second, 5*time.Millisecond)
+	return order
+}
+
+func restock(t *testing.T, rp interface {
+	GetProduct(id string) (models.Product, error)
+	ApplyCatalogueChange(change models.CatalogueChange) (models.Product, error)
+}, amount int) {
+	product, err := rp.GetProduct(backorderedProduct)
+	assert.Nil(t, err)
+	_, err = rp.ApplyCatalogueChange(models.CatalogueChange{Product: product, StockDelta: amount})
+	assert.Nil(t, err)
+}
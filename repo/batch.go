@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/orders-app/models"
+)
+
+// ErrBatchRejected is returned when an all-or-nothing batch is not placed because one of its items is invalid
+var ErrBatchRejected = errors.New("batch rejected, no order was placed")
+
+// errNotPlaced is reported for the valid items of a rejected all-or-nothing batch
+var errNotPlaced = errors.New("not placed, another item of the batch is invalid")
+
+// CreateOrders creates one order per item and enqueues them together, returning the outcome of each item.
+// Best-effort batches place every valid item, all-or-nothing batches place either all items or none.
+func (r *repo) CreateOrders(ctx context.Context, items []models.Item, atomic bool) ([]models.BatchEntry, error) {
+	if len(items) == 0 {
+		return nil, errors.New("batch must contain at least one item")
+	}
+	if len(items) > r.maxBatchSize {
+		return nil, fmt.Errorf("batch of %d items exceeds the maximum of %d", len(items), r.maxBatchSize)
+	}
+
+	entries := make([]models.BatchEntry, len(items))
+	var orders []models.Order
+	var indexes []int
+	for i, item := range items {
+		if err := r.validateItem(item); err != nil {
+			entries[i].Error = err.Error()
+			continue
+		}
+		order := models.NewOrder(item)
+		order.Owner = ownerFrom(ctx)
+		orders = append(orders, order)
+		indexes = append(indexes, i)
+	}
+	if atomic && len(orders) < len(items) {
+		for _, i := range indexes {
+			entries[i].Error = errNotPlaced.Error()
+		}
+		return entries, ErrBatchRejected
+	}
+	if len(orders) == 0 {
+		return entries, nil
+	}
+
+	// store the orders before handing them over so the worker's results are never overwritten
+	events := make([]models.Event, len(orders))
+	for i, order := range orders {
+		events[i] = models.NewOrderEvent(models.EventType_OrderCreated, order)
+	}
+	r.orders.UpsertAll(orders, events...)
+
+	// the batch is enqueued in one call, so a full queue rejects all of its orders
+	if err := r.enqueue(ctx, orders...); err != nil {
+		r.rejectAll(orders, err)
+		if atomic {
+			return nil, err
+		}
+		for _, i := range indexes {
+			entries[i].Error = err.Error()
+		}
+		return entries, nil
+	}
+	for j := range orders {
+		entries[indexes[j]].Order = &orders[j]
+	}
+	return entries, nil
+}
+
+// rejectAll removes orders which could not be enqueued
+func (r *repo) rejectAll(orders []models.Order, err error) {
+	ids := make([]string, len(orders))
+	events := make([]models.Event, len(orders))
+	for i, order := range orders {
+		rejected := order
+		rejected.Status = string(models.OrderStatus_Rejected)
+		rejected.Error = err.Error()
+		ids[i] = order.ID
+		events[i] = models.NewOrderEvent(models.EventType_OrderRejected, rejected)
+	}
+	r.orders.DeleteAll(ids, events...)
+}
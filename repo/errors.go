@@ -0,0 +1,12 @@
+package repo
+
+import "errors"
+
+// ErrNotFound is returned when the entity a request refers to does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalid is returned for requests which fail validation
+var ErrInvalid = errors.New("invalid request")
+
+// ErrConflict is returned for requests which are not allowed in the current state of the entity
+var ErrConflict = errors.New("conflict")
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/models"
+)
+
+// ImportProducts applies imported products to the catalogue and reports the changes.
+// Upserts create and update products, replacements also remove the products missing from the import
+// and dry runs report what an upsert would change without applying it. Products holding reserved stock
+// or waiting backorders are never removed, a replacement keeps them and reports them instead.
+func (r *repo) ImportProducts(products []models.Product, mode models.ImportMode) (models.ImportReport, error) {
+	if mode != models.ImportMode_Upsert && mode != models.ImportMode_Replace && mode != models.ImportMode_DryRun {
+		return models.ImportReport{}, fmt.Errorf("invalid import mode %s, want upsert, replace or dry-run", mode)
+	}
+	for {
+		report := models.ImportReport{Mode: mode, Created: []string{}, Updated: []string{}, Removed: []string{}}
+		current := make(map[string]models.Product)
+		for _, p := range r.products.GetAll() {
+			current[p.ID] = p
+		}
+		var swaps []db.ProductSwap
+		var events []models.Event
+		for _, p := range products {
+			existing, ok := current[p.ID]
+			delete(current, p.ID)
+			// reservations are not part of the catalogue, the stock they hold is kept
+			p.Reserved = existing.Reserved
+			switch {
+			case !ok:
+				report.Created = append(report.Created, p.ID)
+			case !existing.Equal(p):
+				report.Updated = append(report.Updated, p.ID)
+			default:
+				continue
+			}
+			swaps = append(swaps, db.ProductSwap{Old: existing, New: p})
+			events = append(events, models.NewProductEvent(models.EventType_ProductUpdated, p))
+		}
+		var removals []models.Product
+		if mode == models.ImportMode_Replace {
+			for _, p := range current {
+				if p.Reserved > 0 || r.backorders.Waiting(p.ID) > 0 {
+					report.Kept = append(report.Kept, p.ID)
+					continue
+				}
+				removals = append(removals, p)
+			}
+			sort.Slice(removals, func(i, j int) bool { return removals[i].ID < removals[j].ID })
+			sort.Strings(report.Kept)
+			for _, p := range removals {
+				report.Removed = append(report.Removed, p.ID)
+				events = append(events, models.NewProductEvent(models.EventType_ProductRemoved, p))
+			}
+		}
+
+		// the products are only changed if none of them changed since they were read, otherwise the import is retried
+		if mode == models.ImportMode_DryRun || r.products.ApplyIf(swaps, removals, events...) {
+			return report, nil
+		}
+	}
+}
+
+// ApplyCatalogueChange applies a change of the catalogue file to the product. The family, name, variety, category, tags,
+// price, reorder threshold and backorder policy are taken from the file, while the stock only moves by the change in
+// the file so stock consumed by orders is kept.
+func (r *repo) ApplyCatalogueChange(change models.CatalogueChange) (models.Product, error) {
+	if change.Product.ID == "" {
+		return models.Product{}, fmt.Errorf("catalogue change without a product id")
+	}
+	for {
+		current, err := r.products.Find(change.Product.ID)
+		if err != nil {
+			// products new to the file, or removed from the app since, are stored as listed
+			if r.products.Insert(change.Product, models.NewProductEvent(models.EventType_ProductUpdated, change.Product)) {
+				return change.Product, nil
+			}
+			continue
+		}
+		updated := current
+		updated.FamilyID = change.Product.FamilyID
+		updated.Name = change.Product.Name
+		updated.Variety = change.Product.Variety
+		updated.Category = change.Product.Category
+		updated.Tags = change.Product.Tags
+		updated.Price = change.Product.Price
+		updated.ReorderThreshold = change.Product.ReorderThreshold
+		updated.AllowBackorder = change.Product.AllowBackorder
+		if !change.Created {
+			updated.Stock = max(current.Stock+change.StockDelta, 0)
+		}
+		if updated.Equal(current) {
+			return current, nil
+		}
+		if r.products.CompareAndSwap(current, updated, models.NewProductEvent(models.EventType_ProductUpdated, updated)) {
+			return updated, nil
+		}
+	}
+}
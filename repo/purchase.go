@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// CreateSupplier validates and stores a new supplier of the given products
+func (r *repo) CreateSupplier(name, email string, productIDs []string) (models.Supplier, error) {
+	if name == "" {
+		return models.Supplier{}, fmt.Errorf("%w: supplier name must not be empty", ErrInvalid)
+	}
+	for _, id := range productIDs {
+		if err := r.products.Exists(id); err != nil {
+			return models.Supplier{}, fmt.Errorf("%w: product %s does not exist", ErrInvalid, id)
+		}
+	}
+	supplier := models.NewSupplier(name, email, productIDs)
+	r.purchases.UpsertSupplier(supplier)
+	return supplier, nil
+}
+
+// GetSuppliers returns all suppliers
+func (r *repo) GetSuppliers() []models.Supplier {
+	return r.purchases.GetAllSuppliers()
+}
+
+// CreatePurchaseOrder creates a draft purchase order of products the supplier delivers
+func (r *repo) CreatePurchaseOrder(supplierID string, lines []models.PurchaseLine) (models.PurchaseOrder, error) {
+	supplier, err := r.purchases.FindSupplier(supplierID)
+	if err != nil {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(lines) == 0 {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order must contain at least one line", ErrInvalid)
+	}
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		switch {
+		case seen[line.ProductID]:
+			return models.PurchaseOrder{}, fmt.Errorf("%w: product %s is listed more than once", ErrInvalid, line.ProductID)
+		case r.products.Exists(line.ProductID) != nil:
+			return models.PurchaseOrder{}, fmt.Errorf("%w: product %s does not exist", ErrInvalid, line.ProductID)
+		case !supplier.Supplies(line.ProductID):
+			return models.PurchaseOrder{}, fmt.Errorf("%w: supplier %s does not deliver product %s", ErrInvalid, supplier.Name, line.ProductID)
+		case line.Quantity < 1:
+			return models.PurchaseOrder{}, fmt.Errorf("%w: quantity of product %s must be at least 1:got %d", ErrInvalid, line.ProductID, line.Quantity)
+		}
+		seen[line.ProductID] = true
+		lines[i].Received = 0
+	}
+	po := models.NewPurchaseOrder(supplierID, lines)
+	r.purchases.Insert(po)
+	return po, nil
+}
+
+// GetPurchaseOrders returns the purchase orders in the given status, or all of them for an empty status
+func (r *repo) GetPurchaseOrders(status models.PurchaseOrderStatus) []models.PurchaseOrder {
+	var pos []models.PurchaseOrder
+	for _, po := range r.purchases.GetAll() {
+		if status == "" || po.Status == status {
+			pos = append(pos, po)
+		}
+	}
+	return pos
+}
+
+// GetPurchaseOrder returns the given purchase order if one exists
+func (r *repo) GetPurchaseOrder(id string) (models.PurchaseOrder, error) {
+	po, err := r.purchases.Find(id)
+	if err != nil {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return po, nil
+}
+
+// UpdatePurchaseOrderStatus sends a draft purchase order or cancels one which did not receive any goods
+func (r *repo) UpdatePurchaseOrderStatus(id string, status models.PurchaseOrderStatus) (models.PurchaseOrder, error) {
+	po, err := r.GetPurchaseOrder(id)
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+	if !po.CanTransition(status) {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order status is %s, it can not become %s", ErrConflict, po.Status, status)
+	}
+	updated := po
+	updated.Status = status
+	updated.Touch()
+	if !r.purchases.CompareAndSwap(po, updated) {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order %s was modified concurrently, please try again", ErrConflict, id)
+	}
+	return updated, nil
+}
+
+// ReceivePurchaseOrder receives goods of a sent purchase order. The stock of the products is incremented
+// together with the purchase order and the audit receipts in one storage operation, receiving more than was
+// ordered is rejected.
+func (r *repo) ReceivePurchaseOrder(id string, lines []models.PurchaseLine, receivedBy string) (models.PurchaseOrder, error) {
+	if len(lines) == 0 {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: receipt must contain at least one line", ErrInvalid)
+	}
+	// the products may change concurrently, e.g. by orders, so retry until they are updated from their latest state
+	for {
+		po, err := r.GetPurchaseOrder(id)
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		if po.Status != models.PurchaseOrderStatus_Sent && po.Status != models.PurchaseOrderStatus_PartiallyReceived {
+			return models.PurchaseOrder{}, fmt.Errorf("%w: purchase order status is %s, only sent purchase orders receive goods", ErrConflict, po.Status)
+		}
+		updated, err := receiveLines(po, lines)
+		if err != nil {
+			return models.PurchaseOrder{}, err
+		}
+		var swaps []db.ProductSwap
+		var receipts []models.StockReceipt
+		var events []models.Event
+		for _, line := range lines {
+			current, err := r.products.Find(line.ProductID)
+			if err != nil {
+				return models.PurchaseOrder{}, fmt.Errorf("%w: %v", ErrConflict, err)
+			}
+			restocked := current
+			restocked.Stock += line.Quantity
+			swaps = append(swaps, db.ProductSwap{Old: current, New: restocked})
+			receipts = append(receipts, models.NewStockReceipt(po.ID, current, restocked, receivedBy))
+			events = append(events, models.NewProductEvent(models.EventType_StockChanged, restocked))
+		}
+		if r.purchases.Receive(r.products, po, updated, swaps, receipts, events...) {
+			return updated, nil
+		}
+	}
+}
+
+// receiveLines returns the purchase order with the received quantities added to its lines
+func receiveLines(po models.PurchaseOrder, received []models.PurchaseLine) (models.PurchaseOrder, error) {
+	updated := po
+	updated.Lines = append([]models.PurchaseLine(nil), po.Lines...)
+	seen := make(map[string]bool)
+	for _, r := range received {
+		i := -1
+		for j, line := range updated.Lines {
+			if line.ProductID == r.ProductID {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			return po, fmt.Errorf("%w: product %s is not part of the purchase order", ErrInvalid, r.ProductID)
+		case seen[r.ProductID]:
+			return po, fmt.Errorf("%w: product %s is listed more than once", ErrInvalid, r.ProductID)
+		case r.Quantity < 1:
+			return po, fmt.Errorf("%w: quantity of product %s must be at least 1:got %d", ErrInvalid, r.ProductID, r.Quantity)
+		case updated.Lines[i].Received+r.Quantity > updated.Lines[i].Quantity:
+			line := updated.Lines[i]
+			return po, fmt.Errorf("%w: receiving %d of product %s exceeds the %d outstanding", ErrInvalid, r.Quantity, r.ProductID, line.Quantity-line.Received)
+		}
+		seen[r.ProductID] = true
+		updated.Lines[i].Received += r.Quantity
+	}
+	updated.Status = models.PurchaseOrderStatus_Received
+	for _, line := range updated.Lines {
+		if line.Received < line.Quantity {
+			updated.Status = models.PurchaseOrderStatus_PartiallyReceived
+		}
+	}
+	updated.Touch()
+	return updated, nil
+}
+
+// GetStockReceipts returns the audit of the goods received for a purchase order
+func (r *repo) GetStockReceipts(id string) ([]models.StockReceipt, error) {
+	if _, err := r.GetPurchaseOrder(id); err != nil {
+		return nil, err
+	}
+	return r.purchases.GetReceipts(id), nil
+}
+
+// DraftReplenishment creates a draft purchase order restocking a product to twice its reorder threshold.
+// Nothing is drafted for products without a threshold or supplier, or which are already on an open purchase order.
+func (r *repo) DraftReplenishment(productID string) (*models.PurchaseOrder, error) {
+	product, err := r.products.Find(productID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if product.ReorderThreshold <= 0 {
+		return nil, nil
+	}
+	for _, po := range r.purchases.GetAll() {
+		if po.IsOpen() && po.Includes(productID) {
+			return nil, nil
+		}
+	}
+	for _, supplier := range r.purchases.GetAllSuppliers() {
+		if !supplier.Supplies(productID) {
+			continue
+		}
+		po := models.NewPurchaseOrder(supplier.ID, []models.PurchaseLine{{
+			ProductID: productID,
+			Quantity:  max(2*product.ReorderThreshold-product.Stock, 1),
+		}})
+		po.AutoGenerated = true
+		r.purchases.Insert(po)
+		logger.Log.Info(fmt.Sprintf("Drafted purchase order %s restocking product %s", po.ID, productID))
+		return &po, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,88 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PurchaseOrder(t *testing.T) {
+	rp := initRepo(t)
+	supplier, err := rp.CreateSupplier("Springs Ltd", "orders@springs.example", []string{existingProduct})
+	assert.Nil(t, err)
+	before, _ := rp.GetProduct(existingProduct)
+
+	po, err := rp.CreatePurchaseOrder(supplier.ID, []models.PurchaseLine{{ProductID: existingProduct, Quantity: 10}})
+	assert.Nil(t, err)
+	assert.Equal(t, models.PurchaseOrderStatus_Draft, po.Status)
+
+	t.Run("drafts do not receive goods", func(t *testing.T) {
+		_, err := rp.ReceivePurchaseOrder(po.ID, []models.PurchaseLine{{ProductID: existingProduct, Quantity: 1}}, "test")
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	po, err = rp.UpdatePurchaseOrderStatus(po.ID, models.PurchaseOrderStatus_Sent)
+	assert.Nil(t, err)
+
+	t.Run("partial receipt", func(t *testing.T) {
+		received, err := rp.ReceivePurchaseOrder(po.ID, []models.PurchaseLine{{ProductID: existingProduct, Quantity: 4}}, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, models.PurchaseOrderStatus_PartiallyReceived, received.Status)
+		product, _ := rp.GetProduct(existingProduct)
+		assert.Equal(t, before.Stock+4, product.Stock)
+	})
+
+	t.Run("receiving more than ordered", func(t *testing.T) {
+		_, err := rp.ReceivePurchaseOrder(po.ID, []models.PurchaseLine{{ProductID: existingProduct, Quantity: 7}}, "test")
+		assert.ErrorIs(t, err, repo.ErrInvalid)
+	})
+
+	t.Run("cancelling after receiving goods", func(t *testing.T) {
+		_, err := rp.UpdatePurchaseOrderStatus(po.ID, models.PurchaseOrderStatus_Cancelled)
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	t.Run("full receipt with audit", func(t *testing.T) {
+		received, err := rp.ReceivePurchaseOrder(po.ID, []models.PurchaseLine{{ProductID: existingProduct, Quantity: 6}}, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, models.PurchaseOrderStatus_Received, received.Status)
+		receipts, err := rp.GetStockReceipts(po.ID)
+		assert.Nil(t, err)
+		assert.Len(t, receipts, 2)
+		assert.Equal(t, before.Stock+4, receipts[1].StockBefore)
+		assert.Equal(t, before.Stock+10, receipts[1].StockAfter)
+		assert.Equal(t, "test", receipts[1].ReceivedBy)
+	})
+
+	t.Run("products the supplier does not deliver", func(t *testing.T) {
+		_, err := rp.CreatePurchaseOrder(supplier.ID, []models.PurchaseLine{{ProductID: "MWLEM", Quantity: 1}})
+		assert.ErrorIs(t, err, repo.ErrInvalid)
+	})
+}
+
+func Test_DraftReplenishment(t *testing.T) {
+	rp := initRepo(t)
+	_, err := rp.CreateSupplier("Springs Ltd", "", []string{existingProduct})
+	assert.Nil(t, err)
+
+	// MWBLU has 20 in stock and a reorder threshold of 5
+	po, err := rp.DraftReplenishment(existingProduct)
+	assert.Nil(t, err)
+	assert.NotNil(t, po)
+	assert.True(t, po.AutoGenerated)
+	assert.Equal(t, 1, po.Lines[0].Quantity)
+
+	t.Run("product on an open purchase order", func(t *testing.T) {
+		again, err := rp.DraftReplenishment(existingProduct)
+		assert.Nil(t, err)
+		assert.Nil(t, again)
+	})
+
+	t.Run("product without supplier", func(t *testing.T) {
+		none, err := rp.DraftReplenishment("MWLEM")
+		assert.Nil(t, err)
+		assert.Nil(t, none)
+	})
+}
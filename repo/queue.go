@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/orders-app/metrics"
+	"github.com/orders-app/models"
+)
+
+// ErrOverloaded is returned when the intake queue stays full for longer than the enqueue timeout
+var ErrOverloaded = errors.New("orders app is overloaded, please try again later")
+
+// ErrClosed is returned when orders are placed while the orders app is closed
+var ErrClosed = errors.New("orders app is closed, please try again later")
+
+// queueStats holds the counters of the intake queue
+type queueStats struct {
+	enqueued atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// QueueMetrics is a snapshot of the intake queue state
+type QueueMetrics struct {
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	Enqueued uint64 `json:"enqueued"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// enqueue places the orders on the intake queue, either all of them or none.
+// It waits at most the enqueue timeout for enough free slots.
+func (r *repo) enqueue(ctx context.Context, orders ...models.Order) error {
+	done := r.closed()
+	select {
+	case <-done:
+		return ErrClosed
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		r.queueStats.rejected.Add(uint64(len(orders)))
+		return err
+	}
+	// a single order can be handed over to the worker directly, a batch must fit into the free slots
+	if len(orders) > 1 && len(orders) > cap(r.incoming) {
+		r.queueStats.rejected.Add(uint64(len(orders)))
+		return fmt.Errorf("%w: %d orders do not fit in the intake queue of %d", ErrOverloaded, len(orders), cap(r.incoming))
+	}
+
+	timer := time.NewTimer(r.enqueueTimeout)
+	defer timer.Stop()
+	reject := func(err error) error {
+		r.queueStats.rejected.Add(uint64(len(orders)))
+		return err
+	}
+
+	// only one producer sends at a time, so the free slots it sees can only grow while it waits
+	select {
+	case r.producer <- struct{}{}:
+		defer func() { <-r.producer }()
+	case <-done:
+		return ErrClosed
+	case <-ctx.Done():
+		return reject(ctx.Err())
+	case <-timer.C:
+		return reject(ErrOverloaded)
+	}
+
+	if len(orders) == 1 {
+		select {
+		case r.incoming <- orders[0]:
+			r.queueStats.enqueued.Add(1)
+			return nil
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return reject(ctx.Err())
+		case <-timer.C:
+			return reject(ErrOverloaded)
+		}
+	}
+
+	for cap(r.incoming)-len(r.incoming) < len(orders) {
+		select {
+		case <-r.dequeued:
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return reject(ctx.Err())
+		case <-timer.C:
+			return reject(ErrOverloaded)
+		}
+	}
+	for _, order := range orders {
+		r.incoming <- order
+	}
+	r.queueStats.enqueued.Add(uint64(len(orders)))
+	return nil
+}
+
+// notifyDequeued wakes up a producer waiting for free slots after the worker took an order
+func (r *repo) notifyDequeued() {
+	select {
+	case r.dequeued <- struct{}{}:
+	default:
+	}
+}
+
+// queueMetrics returns a snapshot of the intake queue state
+func (r *repo) queueMetrics() QueueMetrics {
+	return QueueMetrics{
+		Depth:    len(r.incoming),
+		Capacity: cap(r.incoming),
+		Enqueued: r.queueStats.enqueued.Load(),
+		Rejected: r.queueStats.rejected.Load(),
+	}
+}
+
+// registerMetrics exposes the repo state in the metrics
+func (r *repo) registerMetrics() {
+	metrics.Register("queue.incoming", func() any { return r.queueMetrics() })
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Enqueue(t *testing.T) {
+	item := models.Item{
+		ProductID: productCode,
+		Amount:    1,
+	}
+
+	t.Run("full queue", func(t *testing.T) {
+		r := newQueueRepo(1)
+		assert.Nil(t, r.enqueue(context.Background(), models.NewOrder(item)))
+		assert.ErrorIs(t, r.enqueue(context.Background(), models.NewOrder(item)), ErrOverloaded)
+
+		m := r.queueMetrics()
+		assert.Equal(t, 1, m.Depth)
+		assert.Equal(t, 1, m.Capacity)
+		assert.Equal(t, uint64(1), m.Enqueued)
+		assert.Equal(t, uint64(1), m.Rejected)
+	})
+
+	t.Run("batch larger than the free slots", func(t *testing.T) {
+		r := newQueueRepo(2)
+		assert.Nil(t, r.enqueue(context.Background(), models.NewOrder(item)))
+		assert.ErrorIs(t, r.enqueue(context.Background(), models.NewOrder(item), models.NewOrder(item)), ErrOverloaded)
+		assert.Equal(t, 1, r.queueMetrics().Depth)
+	})
+
+	t.Run("batch waits for free slots", func(t *testing.T) {
+		r := newQueueRepo(2)
+		r.enqueueTimeout = time.Second
+		assert.Nil(t, r.enqueue(context.Background(), models.NewOrder(item)))
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			<-r.incoming
+			r.notifyDequeued()
+		}()
+		assert.Nil(t, r.enqueue(context.Background(), models.NewOrder(item), models.NewOrder(item)))
+		assert.Equal(t, 2, r.queueMetrics().Depth)
+	})
+
+	t.Run("unbuffered queue", func(t *testing.T) {
+		r := newQueueRepo(0)
+		received := make(chan models.Order, 1)
+		go func() { received <- <-r.incoming }()
+		order := models.NewOrder(item)
+		assert.Nil(t, r.enqueue(context.Background(), order))
+		assert.Equal(t, order.ID, (<-received).ID)
+		assert.ErrorIs(t, r.enqueue(context.Background(), models.NewOrder(item), models.NewOrder(item)), ErrOverloaded)
+	})
+
+	t.Run("cancelled request", func(t *testing.T) {
+		r := newQueueRepo(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, r.enqueue(ctx, models.NewOrder(item)), context.Canceled)
+	})
+
+	t.Run("closed app", func(t *testing.T) {
+		r := newQueueRepo(1)
+		close(r.done)
+		assert.ErrorIs(t, r.enqueue(context.Background(), models.NewOrder(item)), ErrClosed)
+	})
+}
+
+// newQueueRepo creates a repo without a worker so nothing drains the intake queue
+func newQueueRepo(capacity int) *repo {
+	return &repo{
+		incoming:       make(chan models.Order, capacity),
+		producer:       make(chan struct{}, 1),
+		dequeued:       make(chan struct{}, 1),
+		enqueueTimeout: 10 * time.Millisecond,
+		done:           make(chan struct{}),
+	}
+}
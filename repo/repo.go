@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync"
+	"time"
 
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
 	"github.com/orders-app/db"
 	"github.com/orders-app/logger"
 	"github.com/orders-app/models"
@@ -13,42 +17,115 @@ import (
 
 // repo holds all the dependencies required for repo operations
 type repo struct {
-	products  *db.ProductDB
-	orders    *db.OrderDB
-	incoming  chan models.Order
-	stats     stats.StatsService
+	products      *db.ProductDB
+	orders        *db.OrderDB
+	purchases     *db.PurchaseDB
+	backorders    *db.BackorderDB
+	reservations  *db.ReservationDB
+	schedule      *db.ScheduleDB
+	subscriptions *db.SubscriptionDB
+	incoming      chan models.Order
+	// producer is held by the producer sending to the intake queue,
+	// dequeued signals it that the worker took an order off the queue
+	producer       chan struct{}
+	dequeued       chan struct{}
+	enqueueTimeout time.Duration
+	maxBatchSize   int
+	queueStats     queueStats
+	waiters        waiters
+	stats          stats.StatsService
+	// done is closed and isOpen unset while the app is closed, both are guarded by openLock
 	done      chan struct{}
 	isOpen    bool
+	openLock  sync.RWMutex
 	processed chan models.Order
+	// cancelLock keeps cancellations from interleaving with the processing of an order
+	cancelLock sync.Mutex
 }
 
 // Repo is the interface we expose to outside packages
 type Repo interface {
-	CreateOrder(item models.Item) (*models.Order, error)
+	CreateOrder(ctx context.Context, item models.Item) (*models.Order, error)
+	CreateOrders(ctx context.Context, items []models.Item, atomic bool) ([]models.BatchEntry, error)
+	ScheduleOrder(ctx context.Context, item models.Item, processAt time.Time) (*models.Order, error)
+	GetScheduledOrders() []models.Order
+	PlaceScheduledOrders() int
 	GetAllProducts() []models.Product
+	GetProduct(id string) (models.Product, error)
+	SearchProducts(query models.ProductQuery) (models.ProductPage, error)
+	GetFamilies() []models.Family
+	GetFamily(id string) (models.Family, error)
+	ApplyCatalogueChange(change models.CatalogueChange) (models.Product, error)
+	ImportProducts(products []models.Product, mode models.ImportMode) (models.ImportReport, error)
 	GetOrder(id string) (models.Order, error)
+	WaitForOrder(ctx context.Context, id string) (models.Order, error)
+	GetOrders(filter models.OrderFilter) []models.Order
+	RangeOrders(filter models.OrderFilter, visit func(models.Order) bool)
 	Close()
 	Open()
 	IsAppOpen() bool
 	GetOrderStats(ctx context.Context) (models.Statistics, error)
-	RequestReversal(orderId string) (*models.Order, error)
+	RequestReversal(ctx context.Context, orderId string) (*models.Order, error)
+	CancelOrder(id string) (models.Order, error)
+	ReturnOrder(orderID string, lines []models.ReturnLine) (models.Return, error)
+	GetReturns(orderID string) ([]models.Return, error)
+	CreateSupplier(name, email string, productIDs []string) (models.Supplier, error)
+	GetSuppliers() []models.Supplier
+	CreatePurchaseOrder(supplierID string, lines []models.PurchaseLine) (models.PurchaseOrder, error)
+	GetPurchaseOrders(status models.PurchaseOrderStatus) []models.PurchaseOrder
+	GetPurchaseOrder(id string) (models.PurchaseOrder, error)
+	UpdatePurchaseOrderStatus(id string, status models.PurchaseOrderStatus) (models.PurchaseOrder, error)
+	ReceivePurchaseOrder(id string, lines []models.PurchaseLine, receivedBy string) (models.PurchaseOrder, error)
+	GetStockReceipts(id string) ([]models.StockReceipt, error)
+	DraftReplenishment(productID string) (*models.PurchaseOrder, error)
+	Reserve(ctx context.Context, item models.Item, ttl time.Duration) (models.Reservation, error)
+	GetReservation(id string) (models.Reservation, error)
+	GetReservations(status models.ReservationStatus) []models.Reservation
+	ConfirmReservation(ctx context.Context, id string) (*models.Order, error)
+	ReleaseReservation(id string) (models.Reservation, error)
+	ExpireReservations() int
+	FulfillBackorders(productID string)
+	CreateSubscription(ctx context.Context, item models.Item, cadence models.Cadence, startAt time.Time) (models.Subscription, error)
+	GetSubscriptions(status models.SubscriptionStatus) []models.Subscription
+	GetSubscription(id string) (models.Subscription, error)
+	UpdateSubscription(id string, amount int, cadence models.Cadence) (models.Subscription, error)
+	UpdateSubscriptionStatus(id string, status models.SubscriptionStatus) (models.Subscription, error)
+	ClaimDueSubscriptions(now time.Time) []models.Subscription
+	RecordSubscriptionRun(run models.SubscriptionRun) error
+	GetSubscriptionRuns(id string) ([]models.SubscriptionRun, error)
 }
 
 // New creates a new Order repo with the correct database dependencies
-func New() (Repo, error) {
+// and an intake queue of the configured capacity, order and stock changes write their events to the outbox.
+// The products are loaded from the catalogue file.
+func New(queue config.Queue, cataloguePath string, outbox *db.Outbox) (Repo, error) {
+	products, err := db.NewProductDBService(outbox, cataloguePath)
+	if err != nil {
+		return nil, err
+	}
 	processed := make(chan models.Order, stats.WorkerCount)
 	done := make(chan struct{})
 	statsService := stats.New(processed, done)
 	o := repo{
-		products:  db.NewProductDBService(),
-		orders:    db.NewOrderDBService(),
-		incoming:  make(chan models.Order),
-		done:      make(chan struct{}),
-		isOpen:    true,
-		stats:     statsService,
-		processed: processed,
-	}
-	go o.processOrders()
+		products:       products,
+		orders:         db.NewOrderDBService(outbox),
+		purchases:      db.NewPurchaseDBService(outbox),
+		backorders:     db.NewBackorderDBService(),
+		reservations:   db.NewReservationDBService(outbox),
+		schedule:       db.NewScheduleDBService(),
+		subscriptions:  db.NewSubscriptionDBService(),
+		incoming:       make(chan models.Order, queue.Capacity),
+		producer:       make(chan struct{}, 1),
+		dequeued:       make(chan struct{}, 1),
+		enqueueTimeout: queue.EnqueueTimeout,
+		maxBatchSize:   queue.MaxBatchSize,
+		done:           make(chan struct{}),
+		isOpen:         true,
+		stats:          statsService,
+		processed:      processed,
+	}
+	o.registerMetrics()
+	go o.processOrders(o.done)
 	return &o, nil
 }
 
@@ -57,41 +134,86 @@ func (r *repo) GetAllProducts() []models.Product {
 	return r.products.GetAll()
 }
 
-// GetProduct returns the given order if one exists
+// GetProduct returns the given product if one exists
+func (r *repo) GetProduct(id string) (models.Product, error) {
+	return r.products.Find(id)
+}
+
+// GetFamilies returns all product families with their variants
+func (r *repo) GetFamilies() []models.Family {
+	return r.products.GetAllFamilies()
+}
+
+// GetFamily returns the given product family with its variants if it exists
+func (r *repo) GetFamily(id string) (models.Family, error) {
+	return r.products.FindFamily(id)
+}
+
+// GetOrder returns the given order if one exists
 func (r *repo) GetOrder(id string) (models.Order, error) {
 	return r.orders.Find(id)
 }
 
+// GetOrders returns the orders matching the filter ordered by creation time
+func (r *repo) GetOrders(filter models.OrderFilter) []models.Order {
+	var orders []models.Order
+	for _, o := range r.orders.GetAll() {
+		if filter.Matches(o) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+// RangeOrders calls visit for every order matching the filter without collecting them first,
+// orders are visited in no particular order until visit returns false
+func (r *repo) RangeOrders(filter models.OrderFilter, visit func(models.Order) bool) {
+	r.orders.Range(func(o models.Order) bool {
+		return !filter.Matches(o) || visit(o)
+	})
+}
+
 // CreateOrder creates a new order for the given item
-func (r *repo) CreateOrder(item models.Item) (*models.Order, error) {
+func (r *repo) CreateOrder(ctx context.Context, item models.Item) (*models.Order, error) {
 	if err := r.validateItem(item); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
 	}
 	order := models.NewOrder(item)
+	order.Owner = ownerFrom(ctx)
+	// store the order before handing it over so the worker's result is never overwritten
+	r.orders.Upsert(order, models.NewOrderEvent(models.EventType_OrderCreated, order))
 
-	select {
-	case r.incoming <- order:
-		r.orders.Upsert(order)
-		return &order, nil
-	case <-r.done:
-		return nil, fmt.Errorf("orders app is closed, please try gain later")
+	if err := r.enqueue(ctx, order); err != nil {
+		r.rejectAll([]models.Order{order}, err)
+		return nil, err
 	}
+	return &order, nil
 }
 
 func (r *repo) Close() {
+	r.openLock.Lock()
+	defer r.openLock.Unlock()
 	close(r.done)
 	r.isOpen = false
 }
 
+// Open restarts order processing, orders still waiting in the intake queue are kept
+// and backorders which fit into the stock restocked while the app was closed are dispatched
 func (r *repo) Open() {
-	r.incoming = make(chan models.Order)
+	r.openLock.Lock()
 	r.done = make(chan struct{})
 	r.isOpen = true
-	go r.processOrders()
+	go r.processOrders(r.done)
+	r.openLock.Unlock()
+	go func() {
+		for _, id := range r.backorders.ProductIDs() {
+			r.FulfillBackorders(id)
+		}
+	}()
 }
 
 // GetOrderStats returns the order statistics of the orders app
-func (r repo) GetOrderStats(ctx context.Context) (models.Statistics, error) {
+func (r *repo) GetOrderStats(ctx context.Context) (models.Statistics, error) {
 	select {
 	case s := <-r.stats.GetStats(ctx):
 		return s, nil
@@ -100,10 +222,21 @@ func (r repo) GetOrderStats(ctx context.Context) (models.Statistics, error) {
 	}
 }
 
-func (r *repo) IsAppOpen() bool { return r.isOpen }
+func (r *repo) IsAppOpen() bool {
+	r.openLock.RLock()
+	defer r.openLock.RUnlock()
+	return r.isOpen
+}
+
+// closed returns the channel which is closed once the app is closed
+func (r *repo) closed() <-chan struct{} {
+	r.openLock.RLock()
+	defer r.openLock.RUnlock()
+	return r.done
+}
 
 // RequestReversal fetches an existing order and updates it for reversal
-func (r repo) RequestReversal(orderId string) (*models.Order, error) {
+func (r *repo) RequestReversal(ctx context.Context, orderId string) (*models.Order, error) {
 	// try to find the order first
 	order, err := r.orders.Find(orderId)
 	if err != nil {
@@ -112,16 +245,84 @@ func (r repo) RequestReversal(orderId string) (*models.Order, error) {
 	if order.Status != string(models.OrderStatus_Completed) {
 		return nil, fmt.Errorf("order status is %s, only completed orders can be requested for reversal", order.Status)
 	}
-	// set reversal requested
+	completed := order
+	// set reversal requested, only one of concurrent requests for the same order wins
 	order.Status = string(models.OrderStatus_ReversalRequested)
+	if !r.orders.CompareAndSwap(completed, order, models.NewOrderEvent(models.EventType_OrderReversalRequested, order)) {
+		return nil, fmt.Errorf("order %s was modified concurrently, please try again", orderId)
+	}
 	// place the order on the incoming orders channel
-	select {
-	case r.incoming <- order:
-		r.orders.Upsert(order)
-		return &order, nil
-	case <-r.done:
-		return nil, fmt.Errorf("sorry, the orders app is closed")
+	if err := r.enqueue(ctx, order); err != nil {
+		failed := completed
+		failed.Error = err.Error()
+		r.orders.Upsert(completed, models.NewOrderEvent(models.EventType_OrderReversalFailed, failed))
+		return nil, err
 	}
+	return &order, nil
+}
+
+// CancelOrder cancels an order which was not processed yet, is scheduled or waits for a restock, no stock is moved.
+// Cancelled orders still waiting in the intake queue are skipped by the worker.
+func (r *repo) CancelOrder(id string) (models.Order, error) {
+	r.cancelLock.Lock()
+	defer r.cancelLock.Unlock()
+	order, err := r.orders.Find(id)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	switch models.OrderStatus(order.Status) {
+	case models.OrderStatus_new, models.OrderStatus_Scheduled, models.OrderStatus_Backordered:
+	default:
+		return models.Order{}, fmt.Errorf("%w: order status is %s, only new, scheduled and backordered orders can be cancelled", ErrConflict, order.Status)
+	}
+	cancelled := order
+	cancelled.Status = string(models.OrderStatus_Cancelled)
+	if !r.cancel(order, cancelled) {
+		return models.Order{}, fmt.Errorf("%w: order %s was modified concurrently, please try again", ErrConflict, id)
+	}
+	switch models.OrderStatus(order.Status) {
+	case models.OrderStatus_Scheduled:
+		r.schedule.Remove(order.ID)
+	case models.OrderStatus_Backordered:
+		r.backorders.Done(order.Item.ProductID, order.ID, false)
+	}
+	r.waiters.notify(id)
+	r.processed <- cancelled
+	return cancelled, nil
+}
+
+// cancel stores the cancelled order, an order confirming a reservation returns the reserved stock to the
+// available stock in the same storage operation. It fails when the order was modified concurrently.
+func (r *repo) cancel(order, cancelled models.Order) bool {
+	events := []models.Event{models.NewOrderEvent(models.EventType_OrderCancelled, cancelled)}
+	if order.ReservationID == "" {
+		return r.orders.CompareAndSwap(order, cancelled, events...)
+	}
+	// the stock may change concurrently, so retry until it is returned from its latest state
+	for {
+		var stock []db.ProductSwap
+		events = events[:1]
+		// the product may have been removed from the catalogue, then there is no stock to return
+		if current, err := r.products.Find(order.Item.ProductID); err == nil {
+			returned := current
+			returned.Stock += order.Item.Amount
+			returned.Reserved = max(current.Reserved-order.Item.Amount, 0)
+			stock = append(stock, db.ProductSwap{Old: current, New: returned})
+			events = append(events, models.NewProductEvent(models.EventType_StockChanged, returned))
+		}
+		if r.orders.CompareAndSwapStock(r.products, order, cancelled, stock, events...) {
+			return true
+		}
+		if current, err := r.orders.Find(order.ID); err != nil || current != order {
+			return false
+		}
+	}
+}
+
+// ownerFrom returns the subject of the caller in the context, empty when the app acts on its own
+func ownerFrom(ctx context.Context) string {
+	principal, _ := auth.FromContext(ctx)
+	return principal.Subject
 }
 
 // validateItem runs validations on a given order
@@ -135,24 +336,51 @@ func (r *repo) validateItem(item models.Item) error {
 	return nil
 }
 
-func (r *repo) processOrders() {
+func (r *repo) processOrders(done <-chan struct{}) {
 	logger.Log.Info("Order processing started")
 
 	for {
 		select {
 		case order := <-r.incoming:
-			r.processOrder(&order)
-			r.orders.Upsert(order)
-			r.processed <- order
-			logger.Log.Info(fmt.Sprintf("Processing order %s completed\n", order.ID))
-		case <-r.done:
+			r.notifyDequeued()
+			if r.handleOrder(order) {
+				logger.Log.Info(fmt.Sprintf("Processing order %s completed\n", order.ID))
+			}
+		case <-done:
 			logger.Log.Warn("Order processing stopped!")
 			return
 		}
 	}
 }
 
-// processOrder is an internal method which completes or rejects an order
+// handleOrder processes an order taken from the intake queue and stores the result,
+// it returns false for orders which were skipped because they were cancelled or are still backordered
+func (r *repo) handleOrder(order models.Order) bool {
+	r.cancelLock.Lock()
+	defer r.cancelLock.Unlock()
+	wasBackordered := order.Status == string(models.OrderStatus_Backordered)
+	if stored, err := r.orders.Find(order.ID); err == nil && stored.Status == string(models.OrderStatus_Cancelled) {
+		logger.Log.Info(fmt.Sprintf("Skipping cancelled order %s", order.ID))
+		return false
+	}
+	r.processOrder(&order)
+	backordered := order.Status == string(models.OrderStatus_Backordered)
+	if wasBackordered {
+		r.backorders.Done(order.Item.ProductID, order.ID, backordered)
+		if backordered {
+			// the stock was taken in the meantime, the order keeps waiting without counting it again
+			return false
+		}
+	} else if backordered {
+		r.backorders.Push(order)
+	}
+	r.orders.Upsert(order, models.NewOrderEvent(models.OrderEventType(order), order))
+	r.waiters.notify(order.ID)
+	r.processed <- order
+	return true
+}
+
+// processOrder is an internal method which completes, backorders or rejects an order
 func (r *repo) processOrder(order *models.Order) {
 	fetchedOrder, err := r.orders.Find(order.ID)
 	if err != nil || fetchedOrder.Status != string(models.OrderStatus_Completed) {
@@ -160,22 +388,42 @@ func (r *repo) processOrder(order *models.Order) {
 	}
 	item := order.Item
 	if order.Status == string(models.OrderStatus_ReversalRequested) {
-		item.Amount = -item.Amount
+		// the returned amount was restocked already
+		item.Amount = -order.Returnable()
+	}
+	// orders confirming a reservation take the reserved stock, their reversals return it to the available stock
+	reserved := order.ReservationID != "" && item.Amount > 0
+	// new orders do not overtake the backorders waiting for the product
+	queued := order.Status == string(models.OrderStatus_new) && r.backorders.Waiting(item.ProductID) > 0
+	// the stock may change concurrently, e.g. by a catalogue reload, so retry until it is updated from its latest state
+	var product models.Product
+	for {
+		current, err := r.products.Find(item.ProductID)
+		if err != nil {
+			order.Status = string(models.OrderStatus_Rejected)
+			order.Error = err.Error()
+			return
+		}
+		order.FamilyID = current.FamilyID
+		product = current
+		switch {
+		case reserved:
+			// the stock was checked when it was reserved
+			product.Reserved = max(current.Reserved-item.Amount, 0)
+		case current.AllowBackorder && item.Amount > 0 && (current.Stock < item.Amount || queued):
+			order.Backorder()
+			return
+		case current.Stock < item.Amount:
+			order.Status = string(models.OrderStatus_Rejected)
+			order.Error = fmt.Sprintf("not enough stock for product %s:got %d, want %d", item.ProductID, current.Stock, item.Amount)
+			return
+		default:
+			product.Stock = current.Stock - item.Amount
+		}
+		if r.products.CompareAndSwap(current, product, models.NewProductEvent(models.EventType_StockChanged, product)) {
+			break
+		}
 	}
-	product, err := r.products.Find(item.ProductID)
-	if err != nil {
-		order.Status = string(models.OrderStatus_Rejected)
-		order.Error = err.Error()
-		return
-	}
-	if product.Stock < item.Amount {
-		order.Status = string(models.OrderStatus_Rejected)
-		order.Error = fmt.Sprintf("not enough stock for product %s:got %d, want %d", item.ProductID, product.Stock, item.Amount)
-		return
-	}
-	remainingStock := product.Stock - item.Amount
-	product.Stock = remainingStock
-	r.products.Upsert(product)
 
 	total := math.Round(float64(order.Item.Amount)*product.Price*100) / 100
 	order.Total = total
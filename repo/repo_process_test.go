@@ -1,9 +1,11 @@
 package repo
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/orders-app/db"
 	"github.com/orders-app/models"
@@ -17,16 +19,15 @@ const productStock = 11
 const concurrentOrders = 10
 
 func Test_ProcessOrder(t *testing.T) {
-	t.Skip("Skipping process Order test")
-
 	prod := &db.ProductDB{}
 	prod.Upsert(models.Product{
 		ID:    productCode,
 		Stock: productStock,
 	})
 	r := &repo{
-		orders:   db.NewOrderDBService(),
-		products: prod,
+		orders:     db.NewOrderDBService(nil),
+		products:   prod,
+		backorders: db.NewBackorderDBService(),
 	}
 	item := models.Item{
 		ProductID: productCode,
@@ -55,3 +56,85 @@ func assertStock(t *testing.T, r *repo, expectedStock int) {
 	assert.Nil(t, err)
 	assert.Equal(t, expectedStock, prod.Stock)
 }
+
+func Test_CancelOrder(t *testing.T) {
+	prod := &db.ProductDB{}
+	prod.Upsert(models.Product{
+		ID:    productCode,
+		Stock: productStock,
+	})
+	r := &repo{
+		orders:         db.NewOrderDBService(nil),
+		products:       prod,
+		backorders:     db.NewBackorderDBService(),
+		reservations:   db.NewReservationDBService(nil),
+		incoming:       make(chan models.Order, 1),
+		producer:       make(chan struct{}, 1),
+		dequeued:       make(chan struct{}, 1),
+		enqueueTimeout: time.Second,
+		done:           make(chan struct{}),
+		processed:      make(chan models.Order, 2),
+	}
+	item := models.Item{
+		ProductID: productCode,
+		Amount:    1,
+	}
+
+	t.Run("queued order", func(t *testing.T) {
+		order := models.NewOrder(item)
+		r.orders.Upsert(order)
+		cancelled, err := r.CancelOrder(order.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(models.OrderStatus_Cancelled), cancelled.Status)
+		assert.Equal(t, cancelled, <-r.processed)
+
+		// the worker skips the order when it takes it off the intake queue
+		assert.False(t, r.handleOrder(order))
+		assertStock(t, r, productStock)
+		stored, _ := r.orders.Find(order.ID)
+		assert.Equal(t, string(models.OrderStatus_Cancelled), stored.Status)
+
+		_, err = r.CancelOrder(order.ID)
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("backordered order", func(t *testing.T) {
+		order := models.NewOrder(item)
+		order.Backorder()
+		r.orders.Upsert(order)
+		r.backorders.Push(order)
+		cancelled, err := r.CancelOrder(order.ID)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, (<-r.processed).BackorderedAt)
+		assert.Equal(t, string(models.OrderStatus_Cancelled), cancelled.Status)
+		assert.Equal(t, 0, r.backorders.Waiting(productCode))
+	})
+
+	t.Run("reserved order", func(t *testing.T) {
+		reservation, err := r.Reserve(context.Background(), item, 0)
+		assert.Nil(t, err)
+		order, err := r.ConfirmReservation(context.Background(), reservation.ID)
+		assert.Nil(t, err)
+		queued := <-r.incoming
+		cancelled, err := r.CancelOrder(order.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, cancelled, <-r.processed)
+
+		// the reserved stock is available again and the worker skips the order
+		assert.False(t, r.handleOrder(queued))
+		product, err := r.products.Find(productCode)
+		assert.Nil(t, err)
+		assert.Equal(t, productStock, product.Stock)
+		assert.Equal(t, 0, product.Reserved)
+	})
+
+	t.Run("processed order", func(t *testing.T) {
+		order := models.NewOrder(item)
+		order.Complete()
+		r.orders.Upsert(order)
+		_, err := r.CancelOrder(order.ID)
+		assert.ErrorIs(t, err, ErrConflict)
+		_, err = r.CancelOrder("blablabla")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -1,10 +1,13 @@
 package repo_test
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
 	"github.com/orders-app/logger"
 	"github.com/orders-app/models"
 	"github.com/orders-app/repo"
@@ -30,7 +33,7 @@ func Test_CreateOrder(t *testing.T) {
 			ProductID: existingProduct,
 			Amount:    500,
 		}
-		order, _ := rp.CreateOrder(item)
+		order, _ := rp.CreateOrder(context.Background(), item)
 		assert.NotNil(t, order)
 		assert.Equal(t, string(models.OrderStatus_new), order.Status)
 		assert.Equal(t, item, order.Item)
@@ -53,7 +56,7 @@ func Test_CreateOrder(t *testing.T) {
 			ProductID: "blablabla",
 			Amount:    5,
 		}
-		order, err := rp.CreateOrder(item)
+		order, err := rp.CreateOrder(context.Background(), item)
 		assert.Nil(t, order)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "does not exist")
@@ -66,7 +69,7 @@ func Test_CreateOrder(t *testing.T) {
 			ProductID: existingProduct,
 			Amount:    -5,
 		}
-		order, err := rp.CreateOrder(item)
+		order, err := rp.CreateOrder(context.Background(), item)
 		assert.Nil(t, order)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "order amount must be at least 1")
@@ -82,14 +85,16 @@ func Test_GetOrder(t *testing.T) {
 			Amount:    5,
 		}
 
-		order, err := rp.CreateOrder(item)
+		order, err := rp.CreateOrder(context.Background(), item)
 		assert.Nil(t, err)
 		assert.NotNil(t, order)
 
+		// the worker may already have processed the order, so only compare its identity
 		fetchedOrder, err := rp.GetOrder(order.ID)
 		assert.Nil(t, err)
 		assert.NotNil(t, order)
-		assert.Equal(t, *order, fetchedOrder)
+		assert.Equal(t, order.ID, fetchedOrder.ID)
+		assert.Equal(t, order.Item, fetchedOrder.Item)
 	})
 
 	t.Run("non-existing order", func(t *testing.T) {
@@ -99,7 +104,7 @@ func Test_GetOrder(t *testing.T) {
 			Amount:    5,
 		}
 
-		order, err := rp.CreateOrder(item)
+		order, err := rp.CreateOrder(context.Background(), item)
 		assert.Nil(t, err)
 		assert.NotNil(t, order)
 
@@ -109,6 +114,61 @@ func Test_GetOrder(t *testing.T) {
 	})
 }
 
+func Test_WaitForOrder(t *testing.T) {
+	t.Run("processed order", func(t *testing.T) {
+		rp := initRepo(t)
+		order, err := rp.CreateOrder(context.Background(), models.Item{
+			ProductID: existingProduct,
+			Amount:    1,
+		})
+		assert.Nil(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		processed, err := rp.WaitForOrder(ctx, order.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(models.OrderStatus_Completed), processed.Status)
+	})
+
+	t.Run("non-existing order", func(t *testing.T) {
+		rp := initRepo(t)
+		_, err := rp.WaitForOrder(context.Background(), "blablabla")
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "no order found")
+	})
+}
+
+func Test_CreateOrders(t *testing.T) {
+	valid := models.Item{ProductID: existingProduct, Amount: 1}
+	invalid := models.Item{ProductID: "blablabla", Amount: 1}
+
+	t.Run("best effort", func(t *testing.T) {
+		rp := initRepo(t)
+		entries, err := rp.CreateOrders(context.Background(), []models.Item{valid, invalid, valid}, false)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 3)
+		assert.NotNil(t, entries[0].Order)
+		assert.Nil(t, entries[1].Order)
+		assert.NotEmpty(t, entries[1].Error)
+		assert.NotNil(t, entries[2].Order)
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		rp := initRepo(t)
+		entries, err := rp.CreateOrders(context.Background(), []models.Item{valid, invalid}, true)
+		assert.ErrorIs(t, err, repo.ErrBatchRejected)
+		assert.Nil(t, entries[0].Order)
+		assert.NotEmpty(t, entries[0].Error)
+		assert.Empty(t, rp.GetOrders(models.OrderFilter{}))
+	})
+
+	t.Run("too many items", func(t *testing.T) {
+		rp := initRepo(t)
+		_, err := rp.CreateOrders(context.Background(), make([]models.Item, 6), false)
+		assert.NotNil(t, err)
+	})
+}
+
 func Test_GetAllProducts(t *testing.T) {
 	t.Run("get products", func(t *testing.T) {
 		rp := initRepo(t)
@@ -118,7 +178,7 @@ func Test_GetAllProducts(t *testing.T) {
 }
 
 func initRepo(t *testing.T) repo.Repo {
-	rp, err := repo.New()
+	rp, err := repo.New(config.Queue{Capacity: 10, EnqueueTimeout: time.Second, MaxBatchSize: 5}, "./input/products.csv", db.NewOutbox())
 	assert.Nil(t, err)
 	return rp
 }
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// reservation lifetimes
+const (
+	defaultReservationTTL = 15 * time.Minute
+	maxReservationTTL     = time.Hour
+)
+
+// Reserve holds stock of a product for the ttl, or the default ttl when zero. The stock is moved from
+// the available to the reserved stock of the product together with storing the reservation.
+func (r *repo) Reserve(ctx context.Context, item models.Item, ttl time.Duration) (models.Reservation, error) {
+	if err := r.validateItem(item); err != nil {
+		return models.Reservation{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if ttl == 0 {
+		ttl = defaultReservationTTL
+	}
+	if ttl < 0 || ttl > maxReservationTTL {
+		return models.Reservation{}, fmt.Errorf("%w: reservation ttl must be positive and at most %s:got %s", ErrInvalid, maxReservationTTL, ttl)
+	}
+	// the stock may change concurrently, e.g. by orders, so retry until it is updated from its latest state
+	for {
+		current, err := r.products.Find(item.ProductID)
+		if err != nil {
+			return models.Reservation{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		if current.Stock < item.Amount {
+			return models.Reservation{}, fmt.Errorf("%w: not enough stock for product %s:got %d, want %d", ErrConflict, item.ProductID, current.Stock, item.Amount)
+		}
+		held := current
+		held.Stock -= item.Amount
+		held.Reserved += item.Amount
+		reservation := models.NewReservation(item, ttl)
+		reservation.Owner = ownerFrom(ctx)
+		if r.reservations.Hold(r.products, reservation, db.ProductSwap{Old: current, New: held}, models.NewProductEvent(models.EventType_StockChanged, held)) {
+			return reservation, nil
+		}
+	}
+}
+
+// GetReservation returns the given reservation if one exists
+func (r *repo) GetReservation(id string) (models.Reservation, error) {
+	reservation, err := r.reservations.Find(id)
+	if err != nil {
+		return models.Reservation{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return reservation, nil
+}
+
+// GetReservations returns the reservations in the given status, or all of them for an empty status
+func (r *repo) GetReservations(status models.ReservationStatus) []models.Reservation {
+	var reservations []models.Reservation
+	for _, reservation := range r.reservations.GetAll() {
+		if status == "" || reservation.Status == status {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations
+}
+
+// ConfirmReservation turns an active reservation into an order. The order takes the reserved stock
+// when it is processed, so the available stock is not checked again.
+func (r *repo) ConfirmReservation(ctx context.Context, id string) (*models.Order, error) {
+	reservation, err := r.GetReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.Status != models.ReservationStatus_Active {
+		return nil, fmt.Errorf("%w: reservation status is %s, only active reservations can be confirmed", ErrConflict, reservation.Status)
+	}
+	if reservation.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w: reservation %s expired", ErrConflict, id)
+	}
+	order := models.NewOrder(reservation.Item)
+	order.ReservationID = reservation.ID
+	order.Owner = reservation.Owner
+	confirmed := reservation
+	confirmed.Status = models.ReservationStatus_Confirmed
+	confirmed.OrderID = order.ID
+	confirmed.Touch()
+	// only one of concurrent confirmations, releases and expiries of the reservation wins
+	if !r.reservations.CompareAndSwap(reservation, confirmed) {
+		return nil, fmt.Errorf("%w: reservation %s was modified concurrently, please try again", ErrConflict, id)
+	}
+	r.orders.Upsert(order, models.NewOrderEvent(models.EventType_OrderCreated, order))
+	if err := r.enqueue(ctx, order); err != nil {
+		r.rejectAll([]models.Order{order}, err)
+		// the reservation holds the stock again until it is confirmed again or expires
+		r.reservations.CompareAndSwap(confirmed, reservation)
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ReleaseReservation returns the stock of an active reservation
+func (r *repo) ReleaseReservation(id string) (models.Reservation, error) {
+	reservation, err := r.GetReservation(id)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	return r.settle(reservation, models.ReservationStatus_Released)
+}
+
+// ExpireReservations returns the stock of the active reservations past their expiry, it returns how many expired
+func (r *repo) ExpireReservations() int {
+	expired := 0
+	for _, reservation := range r.reservations.Expired(time.Now()) {
+		if _, err := r.settle(reservation, models.ReservationStatus_Expired); err != nil {
+			// the reservation was confirmed or released in the meantime
+			continue
+		}
+		logger.Log.Info(fmt.Sprintf("Reservation %s expired", reservation.ID))
+		expired++
+	}
+	return expired
+}
+
+// settle ends an active reservation with the given status, returning its stock to the product
+func (r *repo) settle(reservation models.Reservation, status models.ReservationStatus) (models.Reservation, error) {
+	for {
+		if reservation.Status != models.ReservationStatus_Active {
+			return models.Reservation{}, fmt.Errorf("%w: reservation status is %s, only active reservations can be released", ErrConflict, reservation.Status)
+		}
+		settled := reservation
+		settled.Status = status
+		settled.Touch()
+		var stock []db.ProductSwap
+		var events []models.Event
+		// the product may have been removed from the catalogue, then there is no stock to return
+		if current, err := r.products.Find(reservation.Item.ProductID); err == nil {
+			returned := current
+			returned.Stock += reservation.Item.Amount
+			returned.Reserved = max(current.Reserved-reservation.Item.Amount, 0)
+			stock = append(stock, db.ProductSwap{Old: current, New: returned})
+			events = append(events, models.NewProductEvent(models.EventType_StockChanged, returned))
+		}
+		if r.reservations.Settle(r.products, reservation, settled, stock, events...) {
+			return settled, nil
+		}
+		var err error
+		if reservation, err = r.GetReservation(reservation.ID); err != nil {
+			return models.Reservation{}, err
+		}
+	}
+}
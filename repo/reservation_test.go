@@ -0,0 +1,94 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reservation(t *testing.T) {
+	item := models.Item{ProductID: existingProduct, Amount: 5}
+
+	t.Run("confirm", func(t *testing.T) {
+		rp := initRepo(t)
+		before, _ := rp.GetProduct(existingProduct)
+		reservation, err := rp.Reserve(context.Background(), item, time.Minute)
+		assert.Nil(t, err)
+		assertStockAndReserved(t, rp, before.Stock-5, 5)
+
+		order, err := rp.ConfirmReservation(context.Background(), reservation.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, reservation.ID, order.ReservationID)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		processed, err := rp.WaitForOrder(ctx, order.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(models.OrderStatus_Completed), processed.Status)
+		assertStockAndReserved(t, rp, before.Stock-5, 0)
+
+		_, err = rp.ConfirmReservation(context.Background(), reservation.ID)
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		rp := initRepo(t)
+		before, _ := rp.GetProduct(existingProduct)
+		reservation, err := rp.Reserve(context.Background(), item, time.Minute)
+		assert.Nil(t, err)
+		released, err := rp.ReleaseReservation(reservation.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, models.ReservationStatus_Released, released.Status)
+		assertStockAndReserved(t, rp, before.Stock, 0)
+
+		_, err = rp.ReleaseReservation(reservation.ID)
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		rp := initRepo(t)
+		before, _ := rp.GetProduct(existingProduct)
+		reservation, err := rp.Reserve(context.Background(), item, time.Millisecond)
+		assert.Nil(t, err)
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, 1, rp.ExpireReservations())
+		assertStockAndReserved(t, rp, before.Stock, 0)
+
+		_, err = rp.ConfirmReservation(context.Background(), reservation.ID)
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	t.Run("released reservations do not expire", func(t *testing.T) {
+		rp := initRepo(t)
+		before, _ := rp.GetProduct(existingProduct)
+		reservation, err := rp.Reserve(context.Background(), item, time.Millisecond)
+		assert.Nil(t, err)
+		_, err = rp.ReleaseReservation(reservation.ID)
+		assert.Nil(t, err)
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, 0, rp.ExpireReservations())
+		assertStockAndReserved(t, rp, before.Stock, 0)
+	})
+
+	t.Run("not enough stock", func(t *testing.T) {
+		rp := initRepo(t)
+		_, err := rp.Reserve(context.Background(), models.Item{ProductID: existingProduct, Amount: 500}, time.Minute)
+		assert.ErrorIs(t, err, repo.ErrConflict)
+	})
+
+	t.Run("ttl too long", func(t *testing.T) {
+		rp := initRepo(t)
+		_, err := rp.Reserve(context.Background(), item, 24*time.Hour)
+		assert.ErrorIs(t, err, repo.ErrInvalid)
+	})
+}
+
+func assertStockAndReserved(t *testing.T, rp repo.Repo, stock, reserved int) {
+	product, err := rp.GetProduct(existingProduct)
+	assert.Nil(t, err)
+	assert.Equal(t, stock, product.Stock)
+	assert.Equal(t, reserved, product.Reserved)
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/orders-app/db"
+	"github.com/orders-app/models"
+)
+
+// ReturnOrder returns part of a completed order, restocking the returned quantity and refunding its share of the total.
+// The lines must refer to the product of the order and may not return more than is left of the order,
+// once all of it was returned the order is reversed.
+func (r *repo) ReturnOrder(orderID string, lines []models.ReturnLine) (models.Return, error) {
+	if len(lines) == 0 {
+		return models.Return{}, fmt.Errorf("%w: return must contain at least one line", ErrInvalid)
+	}
+	// the order and the product may change concurrently, so retry until they are updated from their latest state
+	for {
+		order, err := r.orders.Find(orderID)
+		if err != nil {
+			return models.Return{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		quantity, err := returnQuantity(order, lines)
+		if err != nil {
+			return models.Return{}, err
+		}
+		if order.Status != string(models.OrderStatus_Completed) {
+			return models.Return{}, fmt.Errorf("%w: order status is %s, only completed orders can be returned", ErrConflict, order.Status)
+		}
+		if quantity > order.Returnable() {
+			return models.Return{}, fmt.Errorf("%w: cannot return %d of product %s, %d of the %d purchased are left to return",
+				ErrConflict, quantity, order.Item.ProductID, order.Returnable(), order.Item.Amount)
+		}
+
+		updated := order
+		updated.Returned += quantity
+		// the last return refunds the rest of the total so the refunds always add up to it
+		refund := math.Round(order.Total*float64(quantity)/float64(order.Item.Amount)*100) / 100
+		if updated.Returnable() == 0 {
+			refund = math.Round((order.Total-order.Refunded)*100) / 100
+			updated.Status = string(models.OrderStatus_Reversed)
+		}
+		updated.Refunded = math.Round((order.Refunded+refund)*100) / 100
+		ret := models.NewReturn(order, quantity, refund)
+
+		events := []models.Event{models.NewOrderEvent(models.EventType_OrderReturned, updated)}
+		var stock []db.ProductSwap
+		if current, err := r.products.Find(order.Item.ProductID); err == nil {
+			restocked := current
+			restocked.Stock += quantity
+			stock = append(stock, db.ProductSwap{Old: current, New: restocked})
+			events = append(events, models.NewProductEvent(models.EventType_StockChanged, restocked))
+		}
+		if r.orders.Return(r.products, order, updated, stock, ret, events...) {
+			r.stats.RecordReturn(updated, ret)
+			return ret, nil
+		}
+	}
+}
+
+// GetReturns lists the returns of an order in the order they were made
+func (r *repo) GetReturns(orderID string) ([]models.Return, error) {
+	if _, err := r.orders.Find(orderID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return r.orders.GetReturns(orderID), nil
+}
+
+// returnQuantity validates the lines of a return and adds up their quantities
+func returnQuantity(order models.Order, lines []models.ReturnLine) (int, error) {
+	quantity := 0
+	for _, line := range lines {
+		if line.ProductID != "" && line.ProductID != order.Item.ProductID {
+			return 0, fmt.Errorf("%w: order %s does not contain product %s", ErrInvalid, order.ID, line.ProductID)
+		}
+		if line.Quantity < 1 {
+			return 0, fmt.Errorf("%w: return quantity must be at least 1:got %d", ErrInvalid, line.Quantity)
+		}
+		quantity += line.Quantity
+	}
+	return quantity, nil
+}
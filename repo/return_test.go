@@ -0,0 +1,79 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReturnOrder(t *testing.T) {
+	rp := initRepo(t)
+	order, err := rp.CreateOrder(context.Background(), models.Item{ProductID: existingProduct, Amount: 5})
+	assert.Nil(t, err)
+	completed := assertStatus(t, rp, order.ID, models.OrderStatus_Completed)
+	assert.Equal(t, 8.95, completed.Total)
+
+	ret, err := rp.ReturnOrder(order.ID, []models.ReturnLine{{Quantity: 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ret.Quantity)
+	assert.Equal(t, 3.58, ret.Refund)
+	product, _ := rp.GetProduct(existingProduct)
+	assert.Equal(t, 17, product.Stock)
+
+	t.Run("over return", func(t *testing.T) {
+		_, err := rp.ReturnOrder(order.ID, []models.ReturnLine{{Quantity: 2}, {ProductID: existingProduct, Quantity: 2}})
+		assert.True(t, errors.Is(err, repo.ErrConflict))
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		_, err := rp.ReturnOrder(order.ID, []models.ReturnLine{{ProductID: "MWMAN", Quantity: 1}})
+		assert.True(t, errors.Is(err, repo.ErrInvalid))
+		_, err = rp.ReturnOrder(order.ID, []models.ReturnLine{{Quantity: 0}})
+		assert.True(t, errors.Is(err, repo.ErrInvalid))
+		_, err = rp.ReturnOrder("blablabla", []models.ReturnLine{{Quantity: 1}})
+		assert.True(t, errors.Is(err, repo.ErrNotFound))
+	})
+
+	t.Run("return the rest", func(t *testing.T) {
+		ret, err := rp.ReturnOrder(order.ID, []models.ReturnLine{{ProductID: existingProduct, Quantity: 3}})
+		assert.Nil(t, err)
+		assert.Equal(t, 5.37, ret.Refund)
+		returned, _ := rp.GetOrder(order.ID)
+		assert.Equal(t, string(models.OrderStatus_Reversed), returned.Status)
+		assert.Equal(t, 5, returned.Returned)
+		assert.Equal(t, 8.95, returned.Refunded)
+		product, _ := rp.GetProduct(existingProduct)
+		assert.Equal(t, 20, product.Stock)
+		returns, err := rp.GetReturns(order.ID)
+		assert.Nil(t, err)
+		assert.Len(t, returns, 2)
+
+		_, err = rp.ReturnOrder(order.ID, []models.ReturnLine{{Quantity: 1}})
+		assert.True(t, errors.Is(err, repo.ErrConflict))
+	})
+
+	t.Run("reverse a partly returned order", func(t *testing.T) {
+		order, err := rp.CreateOrder(context.Background(), models.Item{ProductID: existingProduct, Amount: 4})
+		assert.Nil(t, err)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Completed)
+		_, err = rp.ReturnOrder(order.ID, []models.ReturnLine{{Quantity: 1}})
+		assert.Nil(t, err)
+		_, err = rp.RequestReversal(context.Background(), order.ID)
+		assert.Nil(t, err)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Reversed)
+		product, _ := rp.GetProduct(existingProduct)
+		assert.Equal(t, 20, product.Stock)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			stats, err := rp.GetOrderStats(context.Background())
+			return err == nil && stats.CompletedOrders == 2 && stats.ReversedOrders == 2 && stats.Revenue == 0
+		}, 3*time.Second, 20*time.Millisecond)
+	})
+}
@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// ScheduleOrder creates an order for the given item which is placed at the given time,
+// orders which are due already are placed right away
+func (r *repo) ScheduleOrder(ctx context.Context, item models.Item, processAt time.Time) (*models.Order, error) {
+	if !processAt.After(time.Now()) {
+		return r.CreateOrder(ctx, item)
+	}
+	if err := r.validateItem(item); err != nil {
+		return nil, err
+	}
+	order := models.NewOrder(item)
+	order.Owner = ownerFrom(ctx)
+	order.Schedule(processAt)
+	r.orders.Upsert(order, models.NewOrderEvent(models.EventType_OrderScheduled, order))
+	r.schedule.Push(order.ID, processAt)
+	return &order, nil
+}
+
+// GetScheduledOrders returns the orders which wait to be placed ordered by the time they are placed at
+func (r *repo) GetScheduledOrders() []models.Order {
+	orders := r.GetOrders(models.OrderFilter{Status: string(models.OrderStatus_Scheduled)})
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].ProcessTime().Before(orders[j].ProcessTime()) })
+	return orders
+}
+
+// PlaceScheduledOrders places the scheduled orders which are due on the intake queue, the earliest first,
+// and returns how many were placed. Orders stay scheduled while the app is closed or the queue is full,
+// and are placed by a later call.
+func (r *repo) PlaceScheduledOrders() int {
+	placed := 0
+	for r.IsAppOpen() {
+		id, ok := r.schedule.Next(time.Now())
+		if !ok {
+			break
+		}
+		order, err := r.orders.Find(id)
+		if err != nil || order.Status != string(models.OrderStatus_Scheduled) {
+			// the order was cancelled
+			r.schedule.Remove(id)
+			continue
+		}
+		due := order
+		due.Status = string(models.OrderStatus_new)
+		if !r.orders.CompareAndSwap(order, due, models.NewOrderEvent(models.EventType_OrderCreated, due)) {
+			continue
+		}
+		if err := r.enqueue(context.Background(), due); err != nil {
+			// keep the order scheduled unless it was cancelled in the meantime, the event tells
+			// the subscribers which saw it created that it waits again
+			if !r.orders.CompareAndSwap(due, order, models.NewOrderEvent(models.EventType_OrderScheduled, order)) {
+				logger.Log.Warn(fmt.Sprintf("Scheduled order %s changed while it was placed, it stays as it is", id))
+			}
+			logger.Log.Warn(fmt.Sprintf("Placing scheduled order %s failed: %v", id, err))
+			break
+		}
+		r.schedule.Remove(id)
+		placed++
+	}
+	return placed
+}
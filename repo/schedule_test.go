@@ -0,0 +1,75 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScheduleOrder(t *testing.T) {
+	rp := initRepo(t)
+	item := models.Item{ProductID: existingProduct, Amount: 1}
+	later, err := rp.ScheduleOrder(context.Background(), item, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	soon, err := rp.ScheduleOrder(context.Background(), item, time.Now().Add(100*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, string(models.OrderStatus_Scheduled), soon.Status)
+
+	scheduled := rp.GetScheduledOrders()
+	assert.Len(t, scheduled, 2)
+	assert.Equal(t, soon.ID, scheduled[0].ID)
+	assert.Equal(t, later.ID, scheduled[1].ID)
+	assert.Equal(t, 0, rp.PlaceScheduledOrders())
+
+	t.Run("due orders wait while the app is closed", func(t *testing.T) {
+		rp.Close()
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(t, 0, rp.PlaceScheduledOrders())
+		assertStatus(t, rp, soon.ID, models.OrderStatus_Scheduled)
+
+		rp.Open()
+		assert.Equal(t, 1, rp.PlaceScheduledOrders())
+		assertStatus(t, rp, soon.ID, models.OrderStatus_Completed)
+		product, _ := rp.GetProduct(existingProduct)
+		assert.Equal(t, 19, product.Stock)
+	})
+
+	t.Run("cancel a scheduled order", func(t *testing.T) {
+		cancelled, err := rp.CancelOrder(later.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(models.OrderStatus_Cancelled), cancelled.Status)
+		assert.Empty(t, rp.GetScheduledOrders())
+	})
+
+	t.Run("times in other zones", func(t *testing.T) {
+		afterNext, err := rp.ScheduleOrder(context.Background(), item, time.Now().Add(2*time.Hour).In(time.FixedZone("west", -12*60*60)))
+		assert.Nil(t, err)
+		next, err := rp.ScheduleOrder(context.Background(), item, time.Now().Add(time.Hour).In(time.FixedZone("east", 14*60*60)))
+		assert.Nil(t, err)
+
+		scheduled := rp.GetScheduledOrders()
+		assert.Len(t, scheduled, 2)
+		assert.Equal(t, next.ID, scheduled[0].ID)
+		assert.Equal(t, afterNext.ID, scheduled[1].ID)
+		assert.Equal(t, 0, rp.PlaceScheduledOrders())
+		for _, order := range scheduled {
+			_, err := rp.CancelOrder(order.ID)
+			assert.Nil(t, err)
+		}
+	})
+
+	t.Run("due orders are placed right away", func(t *testing.T) {
+		order, err := rp.ScheduleOrder(context.Background(), item, time.Now().Add(-time.Minute))
+		assert.Nil(t, err)
+		assert.Empty(t, order.ProcessAt)
+		assertStatus(t, rp, order.ID, models.OrderStatus_Completed)
+	})
+
+	t.Run("invalid item", func(t *testing.T) {
+		_, err := rp.ScheduleOrder(context.Background(), models.Item{ProductID: existingProduct}, time.Now().Add(time.Hour))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/orders-app/models"
+)
+
+// SearchProducts returns the page of products matching the query. The text is looked up in the search index,
+// the remaining filters are applied to its matches before they are sorted and paged.
+func (r *repo) SearchProducts(query models.ProductQuery) (models.ProductPage, error) {
+	less, err := productOrder(query.Sort)
+	if err != nil {
+		return models.ProductPage{}, err
+	}
+	if query.Page < 1 || query.PageSize < 1 {
+		return models.ProductPage{}, fmt.Errorf("invalid page %d of size %d, both must be at least 1", query.Page, query.PageSize)
+	}
+	if query.MaxPrice != 0 && query.MaxPrice < query.MinPrice {
+		return models.ProductPage{}, fmt.Errorf("invalid price range, maxPrice %.2f is below minPrice %.2f", query.MaxPrice, query.MinPrice)
+	}
+
+	candidates := r.products.GetAll()
+	if strings.TrimSpace(query.Text) != "" {
+		candidates = r.products.Search(query.Text)
+	}
+	var matches []models.Product
+	for _, p := range candidates {
+		if query.Matches(p) {
+			matches = append(matches, p)
+		}
+	}
+	// the candidates are ordered by ID, which breaks ties
+	sort.SliceStable(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	page := models.ProductPage{Products: []models.Product{}, Total: len(matches)}
+	start := (query.Page - 1) * query.PageSize
+	if start < len(matches) {
+		page.Products = matches[start:min(start+query.PageSize, len(matches))]
+	}
+	return page, nil
+}
+
+// productOrder returns how products are ordered for a sort query parameter
+func productOrder(sortBy string) (func(a, b models.Product) bool, error) {
+	descending := strings.HasPrefix(sortBy, "-")
+	var less func(a, b models.Product) bool
+	switch strings.TrimPrefix(sortBy, "-") {
+	case "":
+		return func(a, b models.Product) bool { return false }, nil
+	case "name":
+		less = func(a, b models.Product) bool {
+			return a.Name+" "+a.Variety < b.Name+" "+b.Variety
+		}
+	case "price":
+		less = func(a, b models.Product) bool { return a.Price < b.Price }
+	case "stock":
+		less = func(a, b models.Product) bool { return a.Stock < b.Stock }
+	default:
+		return nil, fmt.Errorf("invalid sort %s, want name, price or stock, optionally prefixed by -", sortBy)
+	}
+	if descending {
+		return func(a, b models.Product) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// CreateSubscription creates an active subscription of the item at the given cadence,
+// it places its first order at the start time or right away when none is given
+func (r *repo) CreateSubscription(ctx context.Context, item models.Item, cadence models.Cadence, startAt time.Time) (models.Subscription, error) {
+	if err := r.validateItem(item); err != nil {
+		return models.Subscription{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if !cadence.Valid() {
+		return models.Subscription{}, fmt.Errorf("%w: invalid cadence %s, want daily, weekly or monthly", ErrInvalid, cadence)
+	}
+	if startAt.IsZero() {
+		startAt = time.Now()
+	}
+	subscription := models.NewSubscription(item, cadence, startAt)
+	subscription.Owner = ownerFrom(ctx)
+	r.subscriptions.Insert(subscription)
+	return subscription, nil
+}
+
+// GetSubscriptions returns the subscriptions with the given status, or all of them for an empty status
+func (r *repo) GetSubscriptions(status models.SubscriptionStatus) []models.Subscription {
+	var subscriptions []models.Subscription
+	for _, s := range r.subscriptions.GetAll() {
+		if status == "" || s.Status == status {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	return subscriptions
+}
+
+// GetSubscription returns the given subscription if it exists
+func (r *repo) GetSubscription(id string) (models.Subscription, error) {
+	subscription, err := r.subscriptions.Find(id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return subscription, nil
+}
+
+// UpdateSubscription changes the amount and the cadence of a subscription which was not cancelled,
+// zero values keep the current ones
+func (r *repo) UpdateSubscription(id string, amount int, cadence models.Cadence) (models.Subscription, error) {
+	if amount < 0 {
+		return models.Subscription{}, fmt.Errorf("%w: order amount must be at least 1:got %d", ErrInvalid, amount)
+	}
+	if cadence != "" && !cadence.Valid() {
+		return models.Subscription{}, fmt.Errorf("%w: invalid cadence %s, want daily, weekly or monthly", ErrInvalid, cadence)
+	}
+	return r.updateSubscription(id, func(s *models.Subscription) error {
+		if s.Status == models.SubscriptionStatus_Cancelled {
+			return fmt.Errorf("%w: subscription is cancelled", ErrConflict)
+		}
+		if amount > 0 {
+			s.Item.Amount = amount
+		}
+		if cadence != "" {
+			s.Cadence = cadence
+		}
+		return nil
+	})
+}
+
+// UpdateSubscriptionStatus pauses, resumes or cancels a subscription.
+// Resumed subscriptions skip the cycles missed while they were paused.
+func (r *repo) UpdateSubscriptionStatus(id string, status models.SubscriptionStatus) (models.Subscription, error) {
+	return r.updateSubscription(id, func(s *models.Subscription) error {
+		if !s.CanTransition(status) {
+			return fmt.Errorf("%w: subscription status is %s, it can not become %s", ErrConflict, s.Status, status)
+		}
+		if status == models.SubscriptionStatus_Active {
+			s.Skip(time.Now())
+		}
+		s.Status = status
+		return nil
+	})
+}
+
+// ClaimDueSubscriptions starts the next cycle of the active subscriptions which are due at the given time
+// and returns them, their next run is moved to the following cycle so every cycle is claimed only once
+func (r *repo) ClaimDueSubscriptions(now time.Time) []models.Subscription {
+	var claimed []models.Subscription
+	for _, s := range r.GetSubscriptions(models.SubscriptionStatus_Active) {
+		due, err := s.Due(now)
+		if err != nil {
+			logger.Log.Warn(fmt.Sprintf("Claiming subscription %s failed: %v", s.ID, err))
+			continue
+		}
+		if !due {
+			continue
+		}
+		updated := s
+		updated.Cycles++
+		updated.Skip(now)
+		updated.Touch()
+		// the subscription was changed in the meantime, it is claimed by a later call if it is still due
+		if r.subscriptions.CompareAndSwap(s, updated) {
+			claimed = append(claimed, updated)
+		}
+	}
+	return claimed
+}
+
+// RecordSubscriptionRun records the run of a cycle of a subscription, counting the failed ones
+func (r *repo) RecordSubscriptionRun(run models.SubscriptionRun) error {
+	for {
+		current, err := r.GetSubscription(run.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		updated := current
+		if run.Failed() {
+			updated.Failures++
+			updated.Touch()
+		}
+		if r.subscriptions.Record(current, updated, run) {
+			return nil
+		}
+	}
+}
+
+// GetSubscriptionRuns returns the runs of the cycles of a subscription in the order they were recorded
+func (r *repo) GetSubscriptionRuns(id string) ([]models.SubscriptionRun, error) {
+	if _, err := r.GetSubscription(id); err != nil {
+		return nil, err
+	}
+	return r.subscriptions.GetRuns(id), nil
+}
+
+// updateSubscription applies the change to the latest state of a subscription, retrying when it changed concurrently
+func (r *repo) updateSubscription(id string, change func(s *models.Subscription) error) (models.Subscription, error) {
+	for {
+		current, err := r.GetSubscription(id)
+		if err != nil {
+			return models.Subscription{}, err
+		}
+		updated := current
+		if err := change(&updated); err != nil {
+			return models.Subscription{}, err
+		}
+		updated.Touch()
+		if r.subscriptions.CompareAndSwap(current, updated) {
+			return updated, nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Subscriptions(t *testing.T) {
+	rp := initRepo(t)
+	item := models.Item{ProductID: existingProduct, Amount: 2}
+	subscription, err := rp.CreateSubscription(context.Background(), item, models.Cadence_Weekly, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, models.SubscriptionStatus_Active, subscription.Status)
+
+	now := time.Now()
+	claimed := rp.ClaimDueSubscriptions(now)
+	assert.Len(t, claimed, 1)
+	assert.Equal(t, 1, claimed[0].Cycles)
+	due, err := claimed[0].Due(now.AddDate(0, 0, 6))
+	assert.Nil(t, err)
+	assert.False(t, due)
+	due, err = claimed[0].Due(now.AddDate(0, 0, 7))
+	assert.Nil(t, err)
+	assert.True(t, due)
+	// a cycle is only claimed once
+	assert.Empty(t, rp.ClaimDueSubscriptions(now))
+
+	t.Run("record runs", func(t *testing.T) {
+		run := models.NewSubscriptionRun(claimed[0])
+		run.Error = "not enough stock"
+		assert.Nil(t, rp.RecordSubscriptionRun(run))
+		runs, err := rp.GetSubscriptionRuns(subscription.ID)
+		assert.Nil(t, err)
+		assert.Len(t, runs, 1)
+		recorded, _ := rp.GetSubscription(subscription.ID)
+		assert.Equal(t, 1, recorded.Failures)
+	})
+
+	t.Run("pause and resume", func(t *testing.T) {
+		paused, err := rp.UpdateSubscriptionStatus(subscription.ID, models.SubscriptionStatus_Paused)
+		assert.Nil(t, err)
+		assert.Equal(t, models.SubscriptionStatus_Paused, paused.Status)
+		assert.Empty(t, rp.ClaimDueSubscriptions(now.AddDate(0, 0, 8)))
+		_, err = rp.UpdateSubscriptionStatus(subscription.ID, models.SubscriptionStatus_Paused)
+		assert.True(t, errors.Is(err, repo.ErrConflict))
+
+		resumed, err := rp.UpdateSubscriptionStatus(subscription.ID, models.SubscriptionStatus_Active)
+		assert.Nil(t, err)
+		assert.Equal(t, models.SubscriptionStatus_Active, resumed.Status)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated, err := rp.UpdateSubscription(subscription.ID, 5, models.Cadence_Monthly)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, updated.Item.Amount)
+		assert.Equal(t, models.Cadence_Monthly, updated.Cadence)
+		_, err = rp.UpdateSubscription(subscription.ID, 0, "yearly")
+		assert.True(t, errors.Is(err, repo.ErrInvalid))
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		cancelled, err := rp.UpdateSubscriptionStatus(subscription.ID, models.SubscriptionStatus_Cancelled)
+		assert.Nil(t, err)
+		assert.Equal(t, models.SubscriptionStatus_Cancelled, cancelled.Status)
+		_, err = rp.UpdateSubscriptionStatus(subscription.ID, models.SubscriptionStatus_Active)
+		assert.True(t, errors.Is(err, repo.ErrConflict))
+		_, err = rp.UpdateSubscription(subscription.ID, 1, "")
+		assert.True(t, errors.Is(err, repo.ErrConflict))
+	})
+
+	t.Run("invalid subscriptions", func(t *testing.T) {
+		_, err := rp.CreateSubscription(context.Background(), item, "yearly", time.Time{})
+		assert.True(t, errors.Is(err, repo.ErrInvalid))
+		_, err = rp.CreateSubscription(context.Background(), models.Item{ProductID: "blablabla", Amount: 1}, models.Cadence_Daily, time.Time{})
+		assert.True(t, errors.Is(err, repo.ErrInvalid))
+		_, err = rp.GetSubscription("blablabla")
+		assert.True(t, errors.Is(err, repo.ErrNotFound))
+	})
+}
+
+func Test_SubscriptionCycles(t *testing.T) {
+	item := models.Item{ProductID: existingProduct, Amount: 1}
+	start := time.Date(2031, time.January, 31, 9, 0, 0, 0, time.Local)
+
+	t.Run("monthly cycles do not drift", func(t *testing.T) {
+		subscription := models.NewSubscription(item, models.Cadence_Monthly, start)
+		subscription.Skip(start)
+		assert.Equal(t, "2031-02-28 09:00:00.000", subscription.NextRunAt)
+		subscription.Skip(time.Date(2031, time.February, 28, 9, 0, 0, 0, time.Local))
+		assert.Equal(t, "2031-03-31 09:00:00.000", subscription.NextRunAt)
+	})
+
+	t.Run("start in another zone", func(t *testing.T) {
+		subscription := models.NewSubscription(item, models.Cadence_Daily, start.In(time.FixedZone("east", 14*60*60)))
+		assert.Equal(t, start.Format("2006-01-02 15:04:05.000"), subscription.NextRunAt)
+	})
+
+	t.Run("invalid next run", func(t *testing.T) {
+		subscription := models.NewSubscription(item, models.Cadence_Daily, start)
+		subscription.NextRunAt = "blablabla"
+		due, err := subscription.Due(start)
+		assert.NotNil(t, err)
+		assert.False(t, due)
+	})
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/orders-app/models"
+)
+
+// waiters keeps track of the callers waiting for orders to be processed
+type waiters struct {
+	byOrder map[string][]chan struct{}
+	lock    sync.Mutex
+}
+
+// add registers a new waiter for the given order
+func (w *waiters) add(orderId string) chan struct{} {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.byOrder == nil {
+		w.byOrder = make(map[string][]chan struct{})
+	}
+	ch := make(chan struct{})
+	w.byOrder[orderId] = append(w.byOrder[orderId], ch)
+	return ch
+}
+
+// remove unregisters a waiter which stopped waiting
+func (w *waiters) remove(orderId string, ch chan struct{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	chans := w.byOrder[orderId]
+	for i, c := range chans {
+		if c == ch {
+			chans = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(chans) == 0 {
+		delete(w.byOrder, orderId)
+		return
+	}
+	w.byOrder[orderId] = chans
+}
+
+// notify wakes up all waiters of the given order
+func (w *waiters) notify(orderId string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, ch := range w.byOrder[orderId] {
+		close(ch)
+	}
+	delete(w.byOrder, orderId)
+}
+
+// isPending checks whether the order still waits to be processed
+func isPending(order models.Order) bool {
+	return order.Status == string(models.OrderStatus_new) ||
+		order.Status == string(models.OrderStatus_ReversalRequested)
+}
+
+// WaitForOrder blocks until the given order has been processed or the context is done,
+// and returns the latest state of the order either way
+func (r *repo) WaitForOrder(ctx context.Context, id string) (models.Order, error) {
+	// register before looking the order up so a concurrent completion is never missed
+	ch := r.waiters.add(id)
+	order, err := r.orders.Find(id)
+	if err != nil || !isPending(order) {
+		r.waiters.remove(id, ch)
+		return order, err
+	}
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		r.waiters.remove(id, ch)
+	}
+	return r.orders.Find(id)
+}
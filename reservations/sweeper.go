@@ -0,0 +1,49 @@
+package reservations
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/repo"
+)
+
+// Sweeper periodically returns the stock of expired reservations
+type Sweeper struct {
+	interval time.Duration
+	repo     repo.Repo
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSweeper starts expiring the reservations of the repo every interval
+func NewSweeper(interval time.Duration, r repo.Repo) *Sweeper {
+	s := &Sweeper{
+		interval: interval,
+		repo:     r,
+		done:     make(chan struct{}),
+	}
+	go s.sweep()
+	return s
+}
+
+// Stop stops expiring reservations
+func (s *Sweeper) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *Sweeper) sweep() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if expired := s.repo.ExpireReservations(); expired > 0 {
+				logger.Log.Info(fmt.Sprintf("Expired %d reservations", expired))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
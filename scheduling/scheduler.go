@@ -0,0 +1,49 @@
+package scheduling
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orders-app/logger"
+	"github.com/orders-app/repo"
+)
+
+// Scheduler periodically places the scheduled orders which are due
+type Scheduler struct {
+	interval time.Duration
+	repo     repo.Repo
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScheduler starts placing the due orders of the repo every interval
+func NewScheduler(interval time.Duration, r repo.Repo) *Scheduler {
+	s := &Scheduler{
+		interval: interval,
+		repo:     r,
+		done:     make(chan struct{}),
+	}
+	go s.schedule()
+	return s
+}
+
+// Stop stops placing scheduled orders
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *Scheduler) schedule() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if placed := s.repo.PlaceScheduledOrders(); placed > 0 {
+				logger.Log.Info(fmt.Sprintf("Placed %d scheduled orders", placed))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
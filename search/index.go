@@ -0,0 +1,101 @@
+package search
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index is an in-memory inverted index from the terms of documents to their IDs.
+// The zero value is an empty index ready to use.
+type Index struct {
+	// postings holds the IDs of the documents containing each term
+	postings map[string]map[string]struct{}
+	// terms holds the terms of each document, so they can be removed when it changes
+	terms map[string][]string
+	lock  sync.RWMutex
+}
+
+// Update replaces the indexed text of a document
+func (idx *Index) Update(id string, text ...string) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.postings == nil {
+		idx.postings = make(map[string]map[string]struct{})
+		idx.terms = make(map[string][]string)
+	}
+	idx.remove(id)
+	terms := Tokenize(strings.Join(text, " "))
+	for _, term := range terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]struct{})
+		}
+		idx.postings[term][id] = struct{}{}
+	}
+	idx.terms[id] = terms
+}
+
+// Remove drops a document from the index
+func (idx *Index) Remove(id string) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	for _, term := range idx.terms[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.terms, id)
+}
+
+// Search returns the IDs of the documents matching every term of the query,
+// a query term matches the indexed terms it is a prefix of
+func (idx *Index) Search(query string) map[string]struct{} {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	var result map[string]struct{}
+	for _, queryTerm := range Tokenize(query) {
+		matches := make(map[string]struct{})
+		for term, ids := range idx.postings {
+			if !strings.HasPrefix(term, queryTerm) {
+				continue
+			}
+			for id := range ids {
+				if result == nil {
+					matches[id] = struct{}{}
+				} else if _, ok := result[id]; ok {
+					matches[id] = struct{}{}
+				}
+			}
+		}
+		result = matches
+		if len(result) == 0 {
+			break
+		}
+	}
+	if result == nil {
+		result = make(map[string]struct{})
+	}
+	return result
+}
+
+// Tokenize splits text into lower case terms of letters and digits, dropping duplicates
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]struct{}, len(fields))
+	terms := fields[:0]
+	for _, f := range fields {
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		terms = append(terms, f)
+	}
+	return terms
+}
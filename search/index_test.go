@@ -0,0 +1,23 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/orders-app/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Index(t *testing.T) {
+	var idx search.Index
+	idx.Update("MWBLU", "Mineral Water", "Blueberry")
+	idx.Update("MWLEM", "Mineral Water", "Lemon-Lime")
+
+	assert.Equal(t, map[string]struct{}{"MWBLU": {}, "MWLEM": {}}, idx.Search("water"))
+	assert.Equal(t, map[string]struct{}{"MWLEM": {}}, idx.Search("min lime"))
+	assert.Empty(t, idx.Search("blueberry lime"))
+
+	idx.Update("MWLEM", "Sparkling Water", "Lemon")
+	assert.Empty(t, idx.Search("lime"))
+	idx.Remove("MWBLU")
+	assert.Empty(t, idx.Search("blue"))
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"os"
 
+	"github.com/orders-app/app"
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/grpcapi"
 	"github.com/orders-app/handlers"
 	"github.com/orders-app/logger"
 	"github.com/orders-app/tracing"
@@ -27,12 +32,33 @@ func init() {
 func main() {
 	logger.Log.Info("Application Started")
 
-	handler, err := handlers.New()
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
-	router := handlers.ConfigureHandler(handler)
-	logger.Log.Info("Listening on localhost:3000...")
-	err = http.ListenAndServe(":3000", router)
+	authenticator, err := auth.NewAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := app.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handler := handlers.New(a, cfg.Events)
+	router := handlers.ConfigureHandler(handler, authenticator, cfg.RateLimits)
+
+	lis, err := net.Listen("tcp", ":"+cfg.GrpcPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	grpcServer := grpcapi.NewServer(a, authenticator)
+	go func() {
+		logger.Log.Info("gRPC listening on localhost:" + cfg.GrpcPort + "...")
+		logger.Log.Fatal(grpcServer.Serve(lis).Error())
+	}()
+
+	logger.Log.Info("Listening on localhost:" + cfg.Port + "...")
+	err = http.ListenAndServe(":"+cfg.Port, router)
+	a.Close()
 	logger.Log.Fatal(err.Error())
 }
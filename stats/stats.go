@@ -20,6 +20,7 @@ type statsService struct {
 
 type StatsService interface {
 	GetStats(ctx context.Context) <-chan models.Statistics
+	RecordReturn(order models.Order, ret models.Return)
 }
 
 func New(processed <-chan models.Order, done <-chan struct{}) StatsService {
@@ -72,25 +73,73 @@ func (s *statsService) reconcile() {
 func (s *statsService) processOrder(order models.Order) models.Statistics {
 	// simulate processing as a costly operation
 	randomSleep()
+	var totals models.Totals
+	switch order.Status {
 	// completed orders increment add to the revenue
-	if order.Status == string(models.OrderStatus_Completed) {
-		return models.Statistics{
+	case string(models.OrderStatus_Completed):
+		totals = models.Totals{
 			CompletedOrders: 1,
 			Revenue:         order.Total,
 		}
+		// fulfilled backorders no longer wait
+		if order.BackorderedAt != "" {
+			totals.BackorderedOrders = -1
+		}
+	// backordered orders wait for a restock
+	case string(models.OrderStatus_Backordered):
+		totals = models.Totals{
+			BackorderedOrders: 1,
+		}
+	// cancelled orders no longer wait for a restock either
+	case string(models.OrderStatus_Cancelled):
+		totals = models.Totals{
+			CancelledOrders: 1,
+		}
+		if order.BackorderedAt != "" {
+			totals.BackorderedOrders = -1
+		}
+	// reversed orders remove from the revenue, except for what their returns already refunded
+	case string(models.OrderStatus_Reversed):
+		totals = models.Totals{
+			ReversedOrders: 1,
+			Revenue:        -(order.Total - order.Refunded),
+		}
+	// otherwise the order is rejected, a rejected backorder no longer waits
+	default:
+		totals = models.Totals{
+			RejectedOrders: 1,
+		}
+		if order.BackorderedAt != "" {
+			totals.BackorderedOrders = -1
+		}
 	}
+	return rollUp(order, totals)
+}
 
-	// reversed orders remove from the revenue
+// RecordReturn removes the refund of a return from the revenue, the order counts as reversed once all of it was returned
+func (s *statsService) RecordReturn(order models.Order, ret models.Return) {
+	totals := models.Totals{Revenue: -ret.Refund}
 	if order.Status == string(models.OrderStatus_Reversed) {
-		return models.Statistics{
-			ReversedOrders: 1,
-			Revenue:        -order.Total,
+		totals.ReversedOrders = 1
+	}
+	go func() {
+		select {
+		case s.pStats <- rollUp(order, totals):
+		case <-s.done:
 		}
+	}()
+}
+
+// rollUp rolls the totals of an order up by the variant it references and its family
+func rollUp(order models.Order, totals models.Totals) models.Statistics {
+	stats := models.Statistics{
+		Totals:    totals,
+		ByVariant: map[string]models.Totals{order.Item.ProductID: totals},
 	}
-	// otherwise the order is rejected
-	return models.Statistics{
-		RejectedOrders: 1,
+	if order.FamilyID != "" {
+		stats.ByFamily = map[string]models.Totals{order.FamilyID: totals}
 	}
+	return stats
 }
 
 // GetStats returns the latest order stats
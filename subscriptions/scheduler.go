@@ -0,0 +1,123 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orders-app/auth"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+)
+
+// orderWait is how long a run waits for its order to be processed before recording it
+const orderWait = 30 * time.Second
+
+// runWorkers is how many runs are in progress at most
+const runWorkers = 4
+
+// Scheduler periodically places the orders of the subscriptions which are due
+type Scheduler struct {
+	interval time.Duration
+	repo     repo.Repo
+	// runs hands the claimed subscriptions to the workers
+	runs     chan models.Subscription
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScheduler starts placing the orders of the due subscriptions of the repo every interval
+func NewScheduler(interval time.Duration, r repo.Repo) *Scheduler {
+	s := &Scheduler{
+		interval: interval,
+		repo:     r,
+		runs:     make(chan models.Subscription),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < runWorkers; i++ {
+		go s.work()
+	}
+	go s.schedule()
+	return s
+}
+
+// Stop stops placing subscription orders, runs in progress are still recorded
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *Scheduler) schedule() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.dispatch(s.repo.ClaimDueSubscriptions(time.Now()))
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// dispatch hands the claimed subscriptions to the workers, waiting while all of them are busy.
+// The claimed cycles which are not handed over when the scheduler stops are recorded as failed.
+func (s *Scheduler) dispatch(claimed []models.Subscription) {
+	for i, subscription := range claimed {
+		select {
+		case s.runs <- subscription:
+		case <-s.done:
+			for _, skipped := range claimed[i:] {
+				run := models.NewSubscriptionRun(skipped)
+				run.Error = "subscription scheduler stopped"
+				s.record(skipped, run)
+			}
+			return
+		}
+	}
+}
+
+func (s *Scheduler) work() {
+	for {
+		select {
+		case subscription := <-s.runs:
+			s.run(subscription)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// run places the order of the current cycle of a subscription and records its outcome once it was processed
+func (s *Scheduler) run(subscription models.Subscription) {
+	run := models.NewSubscriptionRun(subscription)
+	// the order belongs to the owner of the subscription, the repo only reads the subject of the principal
+	owner := auth.WithPrincipal(context.Background(), auth.Principal{Subject: subscription.Owner})
+	ctx, cancel := context.WithTimeout(owner, orderWait)
+	defer cancel()
+	order, err := s.repo.CreateOrder(ctx, subscription.Item)
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.OrderID = order.ID
+		processed, err := s.repo.WaitForOrder(ctx, order.ID)
+		if err == nil {
+			run.OrderStatus = processed.Status
+			if processed.Status == string(models.OrderStatus_Rejected) {
+				run.Error = processed.Error
+			}
+		}
+	}
+	s.record(subscription, run)
+}
+
+// record records the run of a cycle of a subscription
+func (s *Scheduler) record(subscription models.Subscription, run models.SubscriptionRun) {
+	if run.Failed() {
+		logger.Log.Warn(fmt.Sprintf("Cycle %d of subscription %s failed: %s", run.Cycle, subscription.ID, run.Error))
+	}
+	if err := s.repo.RecordSubscriptionRun(run); err != nil {
+		logger.Log.Warn(fmt.Sprintf("Recording cycle %d of subscription %s failed: %v", run.Cycle, subscription.ID, err))
+	}
+}
@@ -0,0 +1,68 @@
+package subscriptions
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/orders-app/auth"
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	logger.InitLogger("test")
+	os.Exit(m.Run())
+}
+
+func Test_Scheduler(t *testing.T) {
+	rp, err := repo.New(config.Queue{Capacity: 10, EnqueueTimeout: time.Second, MaxBatchSize: 5}, "./input/products.csv", db.NewOutbox())
+	assert.Nil(t, err)
+	customer := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "customer", Role: auth.Role_Customer})
+	weekly, err := rp.CreateSubscription(customer, models.Item{ProductID: "MWBLU", Amount: 2}, models.Cadence_Weekly, time.Time{})
+	assert.Nil(t, err)
+	outOfStock, err := rp.CreateSubscription(context.Background(), models.Item{ProductID: "MWBLU", Amount: 500}, models.Cadence_Daily, time.Time{})
+	assert.Nil(t, err)
+	later, err := rp.CreateSubscription(context.Background(), models.Item{ProductID: "MWBLU", Amount: 1}, models.Cadence_Daily, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	s := NewScheduler(10*time.Millisecond, rp)
+	defer s.Stop()
+
+	var runs []models.SubscriptionRun
+	assert.Eventually(t, func() bool {
+		runs, _ = rp.GetSubscriptionRuns(weekly.ID)
+		return len(runs) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, runs[0].Cycle)
+	assert.Equal(t, string(models.OrderStatus_Completed), runs[0].OrderStatus)
+	assert.False(t, runs[0].Failed())
+	// the order belongs to the owner of the subscription
+	order, err := rp.GetOrder(runs[0].OrderID)
+	assert.Nil(t, err)
+	assert.Equal(t, "customer", order.Owner)
+
+	assert.Eventually(t, func() bool {
+		runs, _ = rp.GetSubscriptionRuns(outOfStock.ID)
+		return len(runs) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, string(models.OrderStatus_Rejected), runs[0].OrderStatus)
+	assert.Contains(t, runs[0].Error, "not enough stock")
+	failed, _ := rp.GetSubscription(outOfStock.ID)
+	assert.Equal(t, 1, failed.Failures)
+
+	// every cycle places a single order
+	time.Sleep(50 * time.Millisecond)
+	runs, _ = rp.GetSubscriptionRuns(weekly.ID)
+	assert.Len(t, runs, 1)
+	runs, _ = rp.GetSubscriptionRuns(later.ID)
+	assert.Empty(t, runs)
+}
@@ -6,10 +6,12 @@ import (
 
 	"github.com/orders-app/logger"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func InitTracer() func() {
@@ -38,3 +40,10 @@ func InitTracer() func() {
 		}
 	}
 }
+
+// StartSpan starts a span for a request handled by the given service, it is shared by the HTTP and gRPC APIs
+func StartSpan(ctx context.Context, serviceName, name string, attrs ...attribute.KeyValue) (context.Context, oteltrace.Span) {
+	ctx, span := otel.Tracer(serviceName).Start(ctx, name)
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
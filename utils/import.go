@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/orders-app/models"
+)
+
+// product columns of a CSV import, matched case insensitively against the header line
+const (
+	columnID          = "ID"
+	columnFamilyID    = "FamilyID"
+	columnProductName = "ProductName"
+	columnVariety     = "Variety"
+	columnCategory    = "Category"
+	columnTags        = "Tags"
+	columnThreshold   = "ReorderThreshold"
+	columnBackorder   = "AllowBackorder"
+	columnStock       = "Stock"
+	columnPrice       = "Price"
+)
+
+// tagSeparator separates the tags within the Tags column
+const tagSeparator = "|"
+
+// requiredColumns must be present in the header of a CSV import,
+// FamilyID, Variety, Category, Tags, ReorderThreshold and AllowBackorder are optional
+var requiredColumns = []string{columnID, columnProductName, columnStock, columnPrice}
+
+// ImportErrors is returned when an import contains invalid lines
+type ImportErrors []models.ImportError
+
+func (e ImportErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, ie := range e {
+		lines[i] = fmt.Sprintf("line %d", ie.Line)
+		if ie.Column != "" {
+			lines[i] += " column " + ie.Column
+		}
+		lines[i] += ": " + ie.Reason
+	}
+	return "invalid products: " + strings.Join(lines, "; ")
+}
+
+// ImportProducts reads a catalogue file
+func ImportProducts(path string) ([]models.Product, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseProductsCSV(f)
+}
+
+// ParseProductsCSV reads products from CSV, mapping the columns by the names in the header line.
+// All invalid lines are reported together as ImportErrors.
+func ParseProductsCSV(r io.Reader) ([]models.Product, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ImportErrors{{Line: 1, Reason: "missing header line"}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	var errs ImportErrors
+	for _, name := range requiredColumns {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			errs = append(errs, models.ImportError{Line: 1, Column: name, Reason: "missing column"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var products []models.Product
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				errs = append(errs, models.ImportError{Line: parseErr.Line, Reason: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+		value := func(column string) string {
+			i, ok := columns[strings.ToLower(column)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		product := models.Product{
+			ID:       value(columnID),
+			FamilyID: value(columnFamilyID),
+			Name:     value(columnProductName),
+			Variety:  value(columnVariety),
+			Category: value(columnCategory),
+			Tags:     parseTags(value(columnTags)),
+		}
+		product.FamilyID = familyID(product)
+		lineErrs := validateProduct(line, product, seen)
+		if stock, err := strconv.Atoi(value(columnStock)); err != nil {
+			lineErrs = append(lineErrs, models.ImportError{Line: line, Column: columnStock, Reason: "not a whole number"})
+		} else {
+			product.Stock = stock
+		}
+		if price, err := strconv.ParseFloat(value(columnPrice), 64); err != nil {
+			lineErrs = append(lineErrs, models.ImportError{Line: line, Column: columnPrice, Reason: "not a number"})
+		} else {
+			product.Price = price
+		}
+		if value := value(columnThreshold); value != "" {
+			if threshold, err := strconv.Atoi(value); err != nil {
+				lineErrs = append(lineErrs, models.ImportError{Line: line, Column: columnThreshold, Reason: "not a whole number"})
+			} else {
+				product.ReorderThreshold = threshold
+			}
+		}
+		if value := value(columnBackorder); value != "" {
+			if allow, err := strconv.ParseBool(value); err != nil {
+				lineErrs = append(lineErrs, models.ImportError{Line: line, Column: columnBackorder, Reason: "not true or false"})
+			} else {
+				product.AllowBackorder = allow
+			}
+		}
+		lineErrs = append(lineErrs, validateAmounts(line, product)...)
+		if len(lineErrs) > 0 {
+			errs = append(errs, lineErrs...)
+			continue
+		}
+		products = append(products, product)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return products, nil
+}
+
+// ParseProductsJSON reads products from a JSON array, the position in the array is reported as the line of an error
+func ParseProductsJSON(r io.Reader) ([]models.Product, error) {
+	var products []models.Product
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return nil, ImportErrors{{Line: 1, Reason: fmt.Sprintf("invalid json: %v", err)}}
+	}
+	var errs ImportErrors
+	seen := make(map[string]int)
+	for i, product := range products {
+		products[i].FamilyID = familyID(product)
+		errs = append(errs, validateProduct(i+1, products[i], seen)...)
+		errs = append(errs, validateAmounts(i+1, product)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return products, nil
+}
+
+// familyID returns the family of the product, derived from its name when the import does not set it
+func familyID(product models.Product) string {
+	if product.FamilyID != "" {
+		return product.FamilyID
+	}
+	return models.FamilyID(product.Name)
+}
+
+// validateProduct checks the identifying columns of a product, seen holds the line of every ID already read
+func validateProduct(line int, product models.Product, seen map[string]int) []models.ImportError {
+	var errs []models.ImportError
+	if product.ID == "" {
+		errs = append(errs, models.ImportError{Line: line, Column: columnID, Reason: "must not be empty"})
+	} else if first, ok := seen[product.ID]; ok {
+		errs = append(errs, models.ImportError{Line: line, Column: columnID, Reason: fmt.Sprintf("duplicate of line %d", first)})
+	} else {
+		seen[product.ID] = line
+	}
+	if product.Name == "" {
+		errs = append(errs, models.ImportError{Line: line, Column: columnProductName, Reason: "must not be empty"})
+	}
+	return errs
+}
+
+func validateAmounts(line int, product models.Product) []models.ImportError {
+	var errs []models.ImportError
+	if product.Stock < 0 {
+		errs = append(errs, models.ImportError{Line: line, Column: columnStock, Reason: "must not be negative"})
+	}
+	if product.Price < 0 {
+		errs = append(errs, models.ImportError{Line: line, Column: columnPrice, Reason: "must not be negative"})
+	}
+	if product.ReorderThreshold < 0 {
+		errs = append(errs, models.ImportError{Line: line, Column: columnThreshold, Reason: "must not be negative"})
+	}
+	return errs
+}
+
+// parseTags splits the Tags column, dropping empty tags
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, tagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,48 @@
+package utils_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/orders-app/models"
+	"github.com/orders-app/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseProductsCSV(t *testing.T) {
+	t.Run("columns mapped by header", func(t *testing.T) {
+		input := "Price,Stock,ID,ProductName,Variety\n1.79,20,MWBLU,Mineral Water,Blueberry\n"
+		products, err := utils.ParseProductsCSV(strings.NewReader(input))
+		assert.Nil(t, err)
+		assert.Equal(t, []models.Product{
+			{ID: "MWBLU", FamilyID: "mineral-water", Name: "Mineral Water", Variety: "Blueberry", Stock: 20, Price: 1.79},
+		}, products)
+	})
+
+	t.Run("explicit family", func(t *testing.T) {
+		input := "ID,FamilyID,ProductName,Stock,Price\nSWPLN,water,Sparkling Water,1,0.99\n"
+		products, err := utils.ParseProductsCSV(strings.NewReader(input))
+		assert.Nil(t, err)
+		assert.Equal(t, "water", products[0].FamilyID)
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		input := "ID,ProductName,Stock,Price\nMWBLU,Mineral Water,many,1.79\nMWBLU,Mineral Water,1,-1\n"
+		_, err := utils.ParseProductsCSV(strings.NewReader(input))
+		var importErrs utils.ImportErrors
+		assert.True(t, errors.As(err, &importErrs))
+		assert.Equal(t, utils.ImportErrors{
+			{Line: 2, Column: "Stock", Reason: "not a whole number"},
+			{Line: 3, Column: "ID", Reason: "duplicate of line 2"},
+			{Line: 3, Column: "Price", Reason: "must not be negative"},
+		}, importErrs)
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		_, err := utils.ParseProductsCSV(strings.NewReader("ID,ProductName,Stock\n"))
+		var importErrs utils.ImportErrors
+		assert.True(t, errors.As(err, &importErrs))
+		assert.Equal(t, "Price", importErrs[0].Column)
+	})
+}
@@ -0,0 +1,281 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+)
+
+// minSecretLength is the shortest secret accepted for signing deliveries
+const minSecretLength = 16
+
+// ErrInvalidWebhook is returned for webhook subscriptions which fail validation
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Dispatcher delivers events to the subscribed webhooks, retrying failed deliveries
+// with exponential backoff until they succeed or end up in the dead-letter list
+type Dispatcher struct {
+	store    *db.WebhookDB
+	client   *http.Client
+	cfg      config.Webhooks
+	work     chan string
+	wake     chan struct{}
+	inFlight sync.Map
+}
+
+// NewDispatcher creates a dispatcher and starts delivering the events it is given
+func NewDispatcher(store *db.WebhookDB, cfg config.Webhooks) *Dispatcher {
+	d := &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		work:   make(chan string),
+		wake:   make(chan struct{}, 1),
+	}
+	go d.schedule()
+	for i := 0; i < cfg.Workers; i++ {
+		go d.deliver()
+	}
+	return d
+}
+
+// CreateWebhook validates and stores a new webhook subscription
+func (d *Dispatcher) CreateWebhook(u string, eventTypes []models.EventType, secret string) (models.Webhook, error) {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return models.Webhook{}, fmt.Errorf("%w: url must be an absolute http(s) url", ErrInvalidWebhook)
+	}
+	if len(eventTypes) == 0 {
+		return models.Webhook{}, fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhook)
+	}
+	for _, t := range eventTypes {
+		if !isKnownEventType(t) {
+			return models.Webhook{}, fmt.Errorf("%w: unknown event type %s", ErrInvalidWebhook, t)
+		}
+	}
+	if len(secret) < minSecretLength {
+		return models.Webhook{}, fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minSecretLength)
+	}
+	webhook := models.NewWebhook(u, eventTypes, secret)
+	d.store.UpsertWebhook(webhook)
+	return webhook, nil
+}
+
+// GetWebhooks lists all webhook subscriptions
+func (d *Dispatcher) GetWebhooks() []models.Webhook {
+	return d.store.GetAllWebhooks()
+}
+
+// DeleteWebhook removes a webhook subscription, its pending deliveries end up in the dead-letter list
+func (d *Dispatcher) DeleteWebhook(id string) error {
+	return d.store.DeleteWebhook(id)
+}
+
+// GetDeliveries lists the deliveries of a webhook with their attempt history
+func (d *Dispatcher) GetDeliveries(webhookID string) ([]models.Delivery, error) {
+	if _, err := d.store.FindWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	return d.store.FindDeliveries(func(delivery models.Delivery) bool {
+		return delivery.WebhookID == webhookID
+	}), nil
+}
+
+// GetDeadLetters lists the deliveries which exhausted all their attempts
+func (d *Dispatcher) GetDeadLetters() []models.Delivery {
+	return d.store.FindDeliveries(func(delivery models.Delivery) bool {
+		return delivery.Status == string(models.DeliveryStatus_Dead)
+	})
+}
+
+// Replay schedules a dead delivery for another round of attempts
+func (d *Dispatcher) Replay(deliveryID string) (models.Delivery, error) {
+	delivery, err := d.store.FindDelivery(deliveryID)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+	if delivery.Status != string(models.DeliveryStatus_Dead) {
+		return models.Delivery{}, fmt.Errorf("delivery status is %s, only dead deliveries can be replayed", delivery.Status)
+	}
+	delivery.Status = string(models.DeliveryStatus_Pending)
+	delivery.Retries = 0
+	delivery.NextAttemptAt = time.Now()
+	d.store.UpsertDelivery(delivery)
+	d.notify()
+	return delivery, nil
+}
+
+// Name identifies the dispatcher as a sink of the relay
+func (d *Dispatcher) Name() string {
+	return "webhooks"
+}
+
+// Publish records a pending delivery of the event for each subscribed webhook.
+// Deliveries are identified by webhook and event, so an event relayed twice is only delivered once.
+func (d *Dispatcher) Publish(e models.Event) error {
+	created := false
+	for _, webhook := range d.store.GetAllWebhooks() {
+		if webhook.Subscribes(e.Type) && d.store.InsertDelivery(models.NewDelivery(webhook.ID, e)) {
+			created = true
+		}
+	}
+	if created {
+		d.notify()
+	}
+	return nil
+}
+
+// notify wakes the scheduler up without waiting for the next poll
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// schedule hands the deliveries which are due over to the workers,
+// then sleeps until the next one is due or a delivery is queued
+func (d *Dispatcher) schedule() {
+	for {
+		var next time.Time
+		for {
+			id, at, ok := d.store.NextDueDelivery(time.Now())
+			if !ok {
+				next = at
+				break
+			}
+			if _, busy := d.inFlight.LoadOrStore(id, struct{}{}); busy {
+				continue
+			}
+			d.work <- id
+		}
+		if next.IsZero() {
+			<-d.wake
+			continue
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-d.wake:
+		}
+		timer.Stop()
+	}
+}
+
+// deliver attempts the deliveries handed over by the scheduler
+func (d *Dispatcher) deliver() {
+	for id := range d.work {
+		// the delivery may have been replayed or settled since it was queued, so make sure it is still due
+		delivery, err := d.store.FindDelivery(id)
+		if err != nil || delivery.Status != string(models.DeliveryStatus_Pending) || delivery.NextAttemptAt.After(time.Now()) {
+			d.inFlight.Delete(id)
+			continue
+		}
+		delivery = d.attempt(delivery)
+		d.inFlight.Delete(id)
+		d.store.UpsertDelivery(delivery)
+		if delivery.Status == string(models.DeliveryStatus_Pending) {
+			d.notify()
+		} else {
+			d.store.PruneDeliveries(d.cfg.History)
+		}
+	}
+}
+
+// attempt sends the delivery once and returns it with the outcome recorded
+func (d *Dispatcher) attempt(delivery models.Delivery) models.Delivery {
+	webhook, err := d.store.FindWebhook(delivery.WebhookID)
+	if err != nil {
+		// the webhook was deleted, there is nobody left to deliver to
+		delivery.Status = string(models.DeliveryStatus_Dead)
+		delivery.RecordAttempt(time.Now(), 0, err)
+		return delivery
+	}
+
+	start := time.Now()
+	statusCode, err := d.send(webhook, delivery)
+	if err == nil && (statusCode < 200 || statusCode > 299) {
+		err = fmt.Errorf("unexpected status code %d", statusCode)
+	}
+	delivery.RecordAttempt(start, statusCode, err)
+
+	if err == nil {
+		delivery.Status = string(models.DeliveryStatus_Succeeded)
+		return delivery
+	}
+	delivery.Retries++
+	if delivery.Retries >= d.cfg.MaxAttempts {
+		delivery.Status = string(models.DeliveryStatus_Dead)
+		logger.Log.Warn(fmt.Sprintf("Webhook delivery %s moved to the dead-letter list: %v", delivery.ID, err))
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Retries))
+	}
+	return delivery
+}
+
+// send posts the signed event to the webhook and returns the response status code
+func (d *Dispatcher) send(webhook models.Webhook, delivery models.Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Orders-Event", string(delivery.Event.Type))
+	req.Header.Set("X-Orders-Delivery", delivery.ID)
+	req.Header.Set("X-Orders-Event-Id", strconv.FormatUint(delivery.Event.ID, 10))
+	req.Header.Set("X-Orders-Signature", "t="+timestamp+",v1="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given retry
+func (d *Dispatcher) backoff(retry int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < retry && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
+
+// Sign computes the hex encoded HMAC-SHA256 signature of a delivery,
+// receivers verify it by signing "<timestamp>.<body>" with their secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func isKnownEventType(eventType models.EventType) bool {
+	for _, t := range models.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
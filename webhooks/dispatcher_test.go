@@ -0,0 +1,169 @@
+package webhooks_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orders-app/config"
+	"github.com/orders-app/db"
+	"github.com/orders-app/logger"
+	"github.com/orders-app/models"
+	"github.com/orders-app/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "0123456789abcdef"
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("test")
+	os.Exit(m.Run())
+}
+
+func Test_Dispatcher(t *testing.T) {
+	t.Run("signed delivery after retry", func(t *testing.T) {
+		var calls atomic.Int32
+		received := make(chan *http.Request, 1)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			timestamp, signature := parseSignature(r.Header.Get("X-Orders-Signature"))
+			assert.Equal(t, webhooks.Sign(secret, timestamp, body), signature)
+			received <- r
+		}))
+		defer receiver.Close()
+
+		d := newDispatcher(3)
+		webhook, err := d.CreateWebhook(receiver.URL, []models.EventType{models.EventType_OrderCompleted}, secret)
+		assert.Nil(t, err)
+
+		assert.Nil(t, d.Publish(newEvent(1, models.EventType_OrderCreated)))
+		assert.Nil(t, d.Publish(newEvent(2, models.EventType_OrderCompleted)))
+		// a relayed duplicate is only delivered once
+		assert.Nil(t, d.Publish(newEvent(2, models.EventType_OrderCompleted)))
+
+		select {
+		case r := <-received:
+			assert.Equal(t, string(models.EventType_OrderCompleted), r.Header.Get("X-Orders-Event"))
+			assert.Equal(t, "2", r.Header.Get("X-Orders-Event-Id"))
+		case <-time.After(2 * time.Second):
+			t.Fatal("delivery not received")
+		}
+		assert.Eventually(t, func() bool {
+			deliveries, err := d.GetDeliveries(webhook.ID)
+			return err == nil && len(deliveries) == 1 &&
+				deliveries[0].Status == string(models.DeliveryStatus_Succeeded) && len(deliveries[0].Attempts) == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("dead letter and replay", func(t *testing.T) {
+		var healthy atomic.Bool
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer receiver.Close()
+
+		d := newDispatcher(2)
+		_, err := d.CreateWebhook(receiver.URL, []models.EventType{models.EventType_OrderReversed}, secret)
+		assert.Nil(t, err)
+		assert.Nil(t, d.Publish(newEvent(1, models.EventType_OrderReversed)))
+
+		var dead []models.Delivery
+		if !assert.Eventually(t, func() bool {
+			dead = d.GetDeadLetters()
+			return len(dead) == 1
+		}, time.Second, 10*time.Millisecond) {
+			return
+		}
+		assert.Len(t, dead[0].Attempts, 2)
+
+		healthy.Store(true)
+		_, err = d.Replay(dead[0].ID)
+		assert.Nil(t, err)
+		assert.Eventually(t, func() bool {
+			return len(d.GetDeadLetters()) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		_, err = d.Replay(dead[0].ID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("history is pruned", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer receiver.Close()
+
+		d := newDispatcherWithHistory(1, 2)
+		webhook, err := d.CreateWebhook(receiver.URL, []models.EventType{models.EventType_OrderCompleted}, secret)
+		assert.Nil(t, err)
+		for id := uint64(1); id <= 5; id++ {
+			assert.Nil(t, d.Publish(newEvent(id, models.EventType_OrderCompleted)))
+		}
+
+		assert.Eventually(t, func() bool {
+			deliveries, err := d.GetDeliveries(webhook.ID)
+			if err != nil || len(deliveries) != 2 {
+				return false
+			}
+			for _, delivery := range deliveries {
+				if delivery.Status != string(models.DeliveryStatus_Succeeded) {
+					return false
+				}
+			}
+			return true
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid webhook", func(t *testing.T) {
+		d := newDispatcher(1)
+		_, err := d.CreateWebhook("blablabla", []models.EventType{models.EventType_OrderCompleted}, secret)
+		assert.ErrorIs(t, err, webhooks.ErrInvalidWebhook)
+		_, err = d.CreateWebhook("http://localhost", []models.EventType{"blablabla"}, secret)
+		assert.ErrorIs(t, err, webhooks.ErrInvalidWebhook)
+		_, err = d.CreateWebhook("http://localhost", []models.EventType{models.EventType_OrderCompleted}, "short")
+		assert.ErrorIs(t, err, webhooks.ErrInvalidWebhook)
+	})
+}
+
+func newDispatcher(maxAttempts int) *webhooks.Dispatcher {
+	return newDispatcherWithHistory(maxAttempts, 100)
+}
+
+func newDispatcherWithHistory(maxAttempts, history int) *webhooks.Dispatcher {
+	return webhooks.NewDispatcher(db.NewWebhookDBService(), config.Webhooks{
+		Workers:        2,
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Timeout:        time.Second,
+		History:        history,
+	})
+}
+
+func newEvent(id uint64, eventType models.EventType) models.Event {
+	e := models.NewOrderEvent(eventType, models.Order{ID: "order"})
+	e.ID = id
+	return e
+}
+
+func parseSignature(header string) (string, string) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			timestamp = v
+		}
+		if v, ok := strings.CutPrefix(part, "v1="); ok {
+			signature = v
+		}
+	}
+	return timestamp, signature
+}